@@ -27,6 +27,10 @@ const (
 var (
 	cfg    *config.Config
 	logger *logrus.Logger
+
+	// CLI: прогрев кэша списком доменов вместо запуска сервиса
+	warmupList string
+	warmupRate int
 )
 
 func main() {
@@ -35,6 +39,13 @@ func main() {
 		logrus.WithError(err).Fatal("service init fail")
 	}
 
+	if warmupList != "" {
+		if err := launchWarmup(); err != nil {
+			logrus.WithError(err).Fatal("cache warmup failed with error")
+		}
+		return
+	}
+
 	// Непосредственно старт сервиса
 	if err := launchService(); err != nil {
 		logrus.WithError(err).Fatal("service failed with error")
@@ -70,6 +81,8 @@ func loadConfig() (*config.Config, error) {
 
 	var pathToCfg string
 	flag.StringVar(&pathToCfg, "config", fmt.Sprintf(configPathTemplate, wd), "Path to config file")
+	flag.StringVar(&warmupList, "warmup", "", "Warm up cache from domain list file ('-' - stdin) and exit")
+	flag.IntVar(&warmupRate, "warmup-rate", 0, "Warm up rate, queries per second (default: warmup.rate from config)")
 	flag.Parse()
 
 	cfg, err := config.Load(pathToCfg)
@@ -146,9 +159,50 @@ func launchService() error {
 
 	<-ctx.Done()
 
+	logger.Info("Stopping service")
+	if err := whois.Stop(); err != nil {
+		return errors.WithMessage(err, "can't stop whois server gracefully")
+	}
+
 	return nil
 }
 
+// launchWarmup - прогрев кэша (redis, disk или memory со snapshot) без запуска listener
+func launchWarmup() error {
+	logger.Infof("Starting cache warmup from %s", warmupList)
+
+	whois, err := whois_server.NewWhoisProxyServer(&cfg.Service, logger)
+	if err != nil {
+		return errors.WithMessagef(err, "can't create new Whois Proxy Server")
+	}
+
+	rate := warmupRate
+	if rate <= 0 {
+		rate = cfg.Service.Warmup.Rate
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		handleSIGINT()
+		cancel()
+	}()
+
+	report, err := whois.WarmupFromFile(ctx, warmupList, rate, func(report whois_server.WarmupReport) {
+		logger.Infof("processed %d, cached %d, fetched %d, failed %d",
+			report.Total, report.Cached, report.Fetched, report.Failed)
+	})
+
+	for domain, e := range report.Failures {
+		logger.Warnf("failed %s: %s", domain, e)
+	}
+
+	if stopErr := whois.Stop(); stopErr != nil {
+		logger.WithError(stopErr).Error("can't stop whois server gracefully")
+	}
+
+	return err
+}
+
 func handleSIGINT() {
 	ch := make(chan os.Signal, 10)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
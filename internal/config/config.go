@@ -28,17 +28,283 @@ type Service struct {
 	Port          string `yaml:"port" required:"true"`
 	MaxCntConnect int    `yaml:"maxCntConnect" required:"true"`
 
-	MaxLenBuffer int `yaml:"maxLenBuffer" required:"true"`
-	ReadTimeout  int `yaml:"readTimeout" required:"true"`
-	WriteTimeout int `yaml:"writeTimeout" required:"true"`
+	MaxLenBuffer    int `yaml:"maxLenBuffer" required:"true"` // байт, запрос клиента
+	MaxResponseSize int `yaml:"maxResponseSize"`              // байт, ответ whois сервера больше - обрезается
+	ReadTimeout     int `yaml:"readTimeout" required:"true"`
+	WriteTimeout    int `yaml:"writeTimeout" required:"true"`
 
-	CacheTTL   int `yaml:"cacheTTL" required:"true"`
-	CacheReset int `yaml:"cacheReset" required:"true"`
+	Timeouts Timeouts `yaml:"timeouts"` // запросы к whois серверам и общий срок ответа клиенту
 
-	ErrorMsgTemplate string              `yaml:"errorMsgTemplate" required:"true"`
+	CacheTTL        int   `yaml:"cacheTTL" required:"true"`
+	CacheReset      int   `yaml:"cacheReset"` // deprecated: полный сброс кэша заменен на cacheSweep
+	CacheSweep      int   `yaml:"cacheSweep"`
+	CacheMaxEntries int   `yaml:"cacheMaxEntries"`
+	CacheMaxBytes   int64 `yaml:"cacheMaxBytes"`
+
+	CacheBackend  CacheBackend  `yaml:"cacheBackend"`
+	CacheSnapshot CacheSnapshot `yaml:"cacheSnapshot"`
+
+	// добавлять в ответ строку-комментарий с возрастом и источником whois ответа
+	CacheAnnotation bool `yaml:"cacheAnnotation"`
+
+	Prefetch Prefetch `yaml:"prefetch"`
+	Warmup   Warmup   `yaml:"warmup"`
+	Peers    Peers    `yaml:"peers"`
+
+	HTTP         HTTP          `yaml:"http"`
+	Invalidation Invalidation  `yaml:"invalidation"`
+	History      History       `yaml:"history"`
+	Watch        Watch         `yaml:"watch"`
+	Batch        Batch         `yaml:"batch"`
+	Availability Availability  `yaml:"availability"`
+	Redaction    Redaction     `yaml:"redaction"`
+	Rewrite      []RewriteRule `yaml:"rewrite"`
+
+	CacheClassTTL  ClassTTL                  `yaml:"cacheClassTTL"`
+	CacheZoneTTL   map[string]ClassTTL       `yaml:"cacheZoneTTL"`
+	ResultPatterns map[string]ResultPatterns `yaml:"resultPatterns"`
+
+	QueryValidation QueryValidation `yaml:"queryValidation"`
+	PublicSuffix    PublicSuffix    `yaml:"publicSuffix"`
+	IDNA            IDNA            `yaml:"idna"`
+
+	ErrorMsgTemplate string              `yaml:"errorMsgTemplate"` // legacy: fmt шаблон ответа на неверный запрос, см. errorTemplates
+	ErrorTemplates   map[string]string   `yaml:"errorTemplates"`   // класс ошибки -> ответ "% Error: ...", {query}, {reason}, {request_id}
 	DefaultWhois     string              `yaml:"defaultWhois" required:"true"`
 	DomainZoneWhois  map[string]string   `yaml:"domainZoneWhois" required:"true"`
-	AddWhoisDescInfo map[string][]string `yaml:"addWhoisDescInfo" required:"true"`
+	Resolvers        Resolvers           `yaml:"resolvers"`        // выбор whois сервера: domainZoneWhois, IANA, DNS, RDAP, defaultWhois
+	AddWhoisDescInfo map[string][]string `yaml:"addWhoisDescInfo"` // legacy: строки после "source:", см. rewrite
+	DescInfoSource   DescInfoSource      `yaml:"descInfoSource"`
+}
+
+// CacheBackend - хранилище кэша: memory (по умолчанию), redis или disk
+type CacheBackend struct {
+	Type  string `yaml:"type"`
+	Redis Redis  `yaml:"redis"`
+	Disk  Disk   `yaml:"disk"`
+}
+
+type Redis struct {
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	Prefix   string `yaml:"prefix"`
+	Timeout  int    `yaml:"timeout"` // ms
+	PoolSize int    `yaml:"poolSize"`
+}
+
+type Disk struct {
+	Path string `yaml:"path"`
+}
+
+// CacheSnapshot - snapshot memory кэша на диск при остановке и раз в interval секунд, загружается при старте
+type CacheSnapshot struct {
+	Path     string `yaml:"path"`
+	Interval int    `yaml:"interval"`
+}
+
+// Prefetch - refresh-ahead популярных записей кэша
+type Prefetch struct {
+	Enable   bool `yaml:"enable"`
+	Interval int  `yaml:"interval"` // сек., как часто искать записи для обновления
+	Window   int  `yaml:"window"`   // сек., за сколько до устаревания обновлять запись
+	MinHits  int  `yaml:"minHits"`  // минимальное число обращений к записи
+	Budget   int  `yaml:"budget"`   // максимум запросов к whois серверам за interval
+	Jitter   int  `yaml:"jitter"`   // %, случайное уменьшение TTL записей
+}
+
+// Warmup - прогрев кэша списком доменов (по домену на строку)
+type Warmup struct {
+	File      string `yaml:"file"`      // путь к файлу, "-" - stdin
+	Rate      int    `yaml:"rate"`      // запросов в секунду
+	AtStartup bool   `yaml:"atStartup"` // прогрев в фоне при старте сервиса
+}
+
+// Peers - обмен кэшем между репликами: владелец ключа выбирается consistent hashing,
+// промахи кэша пересылаются владельцу по внутреннему протоколу
+type Peers struct {
+	Enable     bool     `yaml:"enable"`
+	Self       string   `yaml:"self"`       // host:port этой реплики, как его видят остальные
+	Listen     string   `yaml:"listen"`     // host:port внутреннего протокола
+	Static     []string `yaml:"static"`     // статический список реплик (host:port)
+	DNSName    string   `yaml:"dnsName"`    // DNS имя для обнаружения реплик (A/AAAA записи)
+	DNSPort    string   `yaml:"dnsPort"`    // порт внутреннего протокола для адресов из DNS
+	DNSRefresh int      `yaml:"dnsRefresh"` // сек., период обновления списка из DNS
+	Timeout    int      `yaml:"timeout"`    // ms, таймаут запроса к реплике
+	Replicas   int      `yaml:"replicas"`   // виртуальных узлов на реплику
+	Secret     string   `yaml:"secret"`     // общий секрет подписи запросов и ответов (HMAC-SHA256), "" - без подписи
+}
+
+// HTTP - HTTP API сервиса (управление кэшем и т.д.)
+type HTTP struct {
+	Listen string `yaml:"listen"` // host:port, "" - HTTP API выключен
+	Token  string `yaml:"token"`  // Authorization: Bearer <token> для административных методов
+}
+
+// Invalidation - рассылка удаления ключей кэша остальным репликам
+type Invalidation struct {
+	Enable    bool     `yaml:"enable"`
+	Secret    string   `yaml:"secret"`    // общий секрет подписи сообщений (HMAC-SHA256)
+	Multicast string   `yaml:"multicast"` // UDP multicast группа, например 239.1.1.1:4345
+	Interface string   `yaml:"interface"` // сетевой интерфейс для multicast
+	Peers     []string `yaml:"peers"`     // или HTTP API остальных реплик: http://10.0.0.2:8043
+	MaxSkew   int      `yaml:"maxSkew"`   // сек., допустимое расхождение времени сообщений
+	Timeout   int      `yaml:"timeout"`   // ms, таймаут HTTP запроса к реплике
+}
+
+// History - история изменений whois ответов
+type History struct {
+	Enable    bool   `yaml:"enable"`
+	Path      string `yaml:"path"`      // каталог хранилища истории
+	Retention int    `yaml:"retention"` // дней, 0 - хранить всегда
+	Prune     int    `yaml:"prune"`     // сек., интервал удаления устаревшей истории
+}
+
+// Watch - отслеживание изменений и окончания регистрации доменов с уведомлениями webhook
+type Watch struct {
+	Enable     bool     `yaml:"enable"`
+	Domains    []string `yaml:"domains"`
+	File       string   `yaml:"file"`       // и/или файл со списком доменов (по домену на строку)
+	Interval   int      `yaml:"interval"`   // сек.
+	Thresholds []int    `yaml:"thresholds"` // дней до окончания регистрации
+	State      string   `yaml:"state"`      // файл состояния между рестартами
+	Webhook    Webhook  `yaml:"webhook"`
+}
+
+// Webhook - получатель уведомлений (JSON POST)
+type Webhook struct {
+	URL     string `yaml:"url"`
+	Timeout int    `yaml:"timeout"` // ms
+	Retries int    `yaml:"retries"`
+	Backoff int    `yaml:"backoff"` // ms, удваивается с каждым повтором
+}
+
+// Batch - несколько запросов в одном TCP соединении и POST /batch в HTTP API
+type Batch struct {
+	Concurrency int `yaml:"concurrency"` // одновременных запросов одного batch
+	MaxItems    int `yaml:"maxItems"`    // запросов в одном batch
+}
+
+// Availability - проверка "свободен ли домен" (available <domain>, GET /available)
+type Availability struct {
+	TTL int `yaml:"ttl"` // сек., время кэширования результата
+}
+
+// Redaction - скрытие персональных данных в ответах
+type Redaction struct {
+	Replacement string          `yaml:"replacement"` // по умолчанию "REDACTED FOR PRIVACY"
+	Trusted     []string        `yaml:"trusted"`     // CIDR клиентов, которым ответы выдаются без скрытия
+	Rules       []RedactionRule `yaml:"rules"`
+}
+
+// RedactionRule - правило применяется, если совпали все заданные условия (listeners, clients, zones)
+type RedactionRule struct {
+	Name        string   `yaml:"name"`
+	Listeners   []string `yaml:"listeners"` // host:port или :port listener'а, на который пришел запрос
+	Clients     []string `yaml:"clients"`   // CIDR клиентов
+	Zones       []string `yaml:"zones"`     // доменные зоны
+	Fields      []string `yaml:"fields"`    // имена полей "name: value", значения которых скрываются
+	Patterns    []string `yaml:"patterns"`  // regexp, совпадения скрываются во всем ответе
+	Replacement string   `yaml:"replacement"`
+}
+
+// RewriteRule - изменение ответа: все заданные условия match должны совпасть
+type RewriteRule struct {
+	Name    string          `yaml:"name"`
+	Match   RewriteMatch    `yaml:"match"`
+	Actions []RewriteAction `yaml:"actions"`
+}
+
+type RewriteMatch struct {
+	Domains   []string          `yaml:"domains"` // "example.ru", "*.example.ru", ".example.ru" (домен и поддомены)
+	Zones     []string          `yaml:"zones"`
+	Upstreams []string          `yaml:"upstreams"` // host или host:port whois сервера
+	Fields    map[string]string `yaml:"fields"`    // domain / registrar / nameserver / status: значение
+}
+
+type RewriteAction struct {
+	Type        string   `yaml:"type"`   // insertBefore / insertAfter / replace / deleteLine / appendFooter
+	Anchor      string   `yaml:"anchor"` // regexp строки
+	Lines       []string `yaml:"lines"`
+	Replacement string   `yaml:"replacement"`
+}
+
+// DescInfoSource - внешний источник строк addWhoisDescInfo, перезагружается на лету
+type DescInfoSource struct {
+	Type     string `yaml:"type"`     // file / http / sqlite, "" - выключен
+	Path     string `yaml:"path"`     // file: .csv / .json / .yaml; sqlite: файл базы
+	URL      string `yaml:"url"`      // http: JSON {"domain": ["line", ...]}
+	Token    string `yaml:"token"`    // http: Authorization: Bearer <token>
+	Query    string `yaml:"query"`    // sqlite: SELECT domain, line ...
+	Interval int    `yaml:"interval"` // сек., проверка изменений / опрос
+	Timeout  int    `yaml:"timeout"`  // ms, http
+}
+
+// QueryValidation - проверка запроса после нормализации (схема, путь, порт URL, e-mail, "www.", точка в конце)
+type QueryValidation struct {
+	MaxLength         int  `yaml:"maxLength"`         // символов в домене (punycode), по умолчанию 253
+	MaxLabelLength    int  `yaml:"maxLabelLength"`    // символов в метке, по умолчанию 63
+	MinTLDLength      int  `yaml:"minTLDLength"`      // символов в зоне, по умолчанию 2
+	AllowUnderscore   bool `yaml:"allowUnderscore"`   // "_" в метках, кроме зоны
+	AllowLooseHyphens bool `yaml:"allowLooseHyphens"` // "-" в начале и конце метки, "--" в 3-4 позиции
+	KeepWWW           bool `yaml:"keepWWW"`           // не отбрасывать "www."
+}
+
+// PublicSuffix - Public Suffix List: сокращение запроса до регистрируемого домена и выбор зоны (co.uk, com.ru)
+type PublicSuffix struct {
+	File    string `yaml:"file"`    // public_suffix_list.dat, "" - встроенный список
+	Refresh int    `yaml:"refresh"` // сек., проверка изменения файла
+}
+
+// IDNA - перевод IDN в punycode (UTS-46): общий профиль и профили зон (самая длинная подходящая зона)
+type IDNA struct {
+	IDNAProfile `yaml:",inline"`
+	Zones       map[string]IDNAProfile `yaml:"zones"`
+}
+
+type IDNAProfile struct {
+	Mode   string `yaml:"mode"`   // transitional (по умолчанию, "ß" -> "ss") / nontransitional (IDNA2008, .de и т.д.)
+	Strict bool   `yaml:"strict"` // правила регистрации IDNA2008 вместо преобразований lookup (регистр, ширина символов), требует bidi
+	Bidi   bool   `yaml:"bidi"`   // bidi правило RFC 5893 для RTL меток
+}
+
+// Resolvers - выбор whois сервера домена: источники по порядку, первый нашедший сервер определяет результат
+type Resolvers struct {
+	Chain         []string `yaml:"chain"`         // static / iana / dns / rdap / default, по умолчанию [static, default]
+	IANAServer    string   `yaml:"ianaServer"`    // host:port, по умолчанию whois.iana.org:43
+	DNSSuffix     string   `yaml:"dnsSuffix"`     // "<tld>.<dnsSuffix>", по умолчанию whois-servers.net
+	RDAPBootstrap string   `yaml:"rdapBootstrap"` // URL bootstrap реестра, по умолчанию https://data.iana.org/rdap/dns.json
+	RDAPTimeout   int      `yaml:"rdapTimeout"`   // ms, HTTP запрос к RDAP
+	TTL           int      `yaml:"ttl"`           // сек., кэш ответов iana / dns / rdap по зоне, по умолчанию сутки
+}
+
+// Timeouts - таймауты запросов к whois серверам: общие и по whois серверам, ключ - "host:port" или "host".
+// Незаданные (0) поля - из общих, затем по умолчанию: dial 30 сек., firstByte и idle - readTimeout, total 1 мин.
+type Timeouts struct {
+	UpstreamTimeouts `yaml:",inline"`
+	Upstreams        map[string]UpstreamTimeouts `yaml:"upstreams"`
+	Request          int                         `yaml:"request"` // ms, срок ответа клиенту на все обращения к репликам и whois серверам, 0 - без ограничения
+}
+
+type UpstreamTimeouts struct {
+	Dial      int `yaml:"dial"`      // ms, установка соединения
+	FirstByte int `yaml:"firstByte"` // ms, от отправки запроса до первого байта ответа
+	Idle      int `yaml:"idle"`      // ms, между частями ответа
+	Total     int `yaml:"total"`     // ms, весь запрос к whois серверу
+}
+
+// ClassTTL - TTL кэша (в секундах) для каждого класса ответа whois сервера, 0 - использовать cacheTTL
+type ClassTTL struct {
+	Found    int `yaml:"found"`
+	NotFound int `yaml:"notFound"`
+	Error    int `yaml:"error"`
+}
+
+// ResultPatterns - регулярные выражения для определения класса ответа конкретного whois сервера
+type ResultPatterns struct {
+	NotFound []string `yaml:"notFound"`
+	Found    []string `yaml:"found"` // признак зарегистрированного домена, используется проверкой доступности
+	Error    []string `yaml:"error"`
 }
 
 func Load(filename string) (Config, error) {
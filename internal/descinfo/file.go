@@ -0,0 +1,100 @@
+package descinfo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// FileProvider - файл CSV (domain,line - строка на запись), JSON или YAML (domain: [lines]).
+// Формат по расширению, изменение определяется по времени модификации и размеру.
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".json", ".yaml", ".yml":
+	default:
+		return nil, errors.Errorf("unsupported desc info file format %q", path)
+	}
+
+	return &FileProvider{path: path}, nil
+}
+
+func (p *FileProvider) Name() string {
+	return "file " + p.path
+}
+
+func (p *FileProvider) Changed() (bool, error) {
+	fi, err := os.Stat(p.path)
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !fi.ModTime().Equal(p.modTime) || fi.Size() != p.size, nil
+}
+
+func (p *FileProvider) Load() (map[string][]string, error) {
+	fi, err := os.Stat(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string][]string{}
+	switch strings.ToLower(filepath.Ext(p.path)) {
+	case ".csv":
+		data, err = parseCSV(string(b))
+	case ".json":
+		err = json.Unmarshal(b, &data)
+	default:
+		err = yaml.Unmarshal(b, &data)
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't parse %s", p.path)
+	}
+
+	p.mu.Lock()
+	p.modTime, p.size = fi.ModTime(), fi.Size()
+	p.mu.Unlock()
+
+	return data, nil
+}
+
+// parseCSV - "domain,line", # - комментарии
+func parseCSV(s string) (map[string][]string, error) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string][]string{}
+	for _, rec := range records {
+		data[rec[0]] = append(data[rec[0]], rec[1])
+	}
+	return data, nil
+}
@@ -0,0 +1,83 @@
+package descinfo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const HTTPTimeoutDefault = 10 * time.Second
+
+// HTTPProvider - JSON (domain: [lines]) по GET запросу, поддерживает ETag (304 - данные не изменились)
+type HTTPProvider struct {
+	url    string
+	token  string
+	client *http.Client
+
+	mu   sync.Mutex
+	etag string
+	last map[string][]string
+}
+
+func NewHTTPProvider(url, token string, timeout time.Duration) *HTTPProvider {
+	if timeout <= 0 {
+		timeout = HTTPTimeoutDefault
+	}
+
+	return &HTTPProvider{url: url, token: token, client: &http.Client{Timeout: timeout}}
+}
+
+func (p *HTTPProvider) Name() string {
+	return "http " + p.url
+}
+
+func (p *HTTPProvider) Load() (map[string][]string, error) {
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	etag, last := p.etag, p.last
+	p.mu.Unlock()
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && last != nil:
+		return last, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't read response")
+	}
+
+	var data map[string][]string
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, errors.WithMessage(err, "can't parse response")
+	}
+
+	p.mu.Lock()
+	p.etag, p.last = resp.Header.Get("ETag"), data
+	p.mu.Unlock()
+
+	return data, nil
+}
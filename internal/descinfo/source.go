@@ -0,0 +1,152 @@
+package descinfo
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/idna"
+)
+
+const ReloadIntervalDefault = time.Minute
+
+type (
+	// Provider - внешний источник собственных строк ответа по доменам (домен -> строки)
+	Provider interface {
+		Name() string
+		Load() (map[string][]string, error)
+	}
+
+	// changeDetector - провайдер, умеющий дешево проверить изменение источника (файл)
+	changeDetector interface {
+		Changed() (bool, error)
+	}
+
+	// Stats - результат последней загрузки
+	Stats struct {
+		Provider string        `json:"provider"`
+		LoadedAt time.Time     `json:"loadedAt"` // последняя успешная загрузка
+		Domains  int           `json:"domains"`
+		Lines    int           `json:"lines"`
+		Duration time.Duration `json:"duration"`
+		Loads    uint64        `json:"loads"`
+		Failures uint64        `json:"failures"`
+		Error    string        `json:"error,omitempty"` // ошибка последней попытки, данные остаются от предыдущей загрузки
+	}
+
+	// Source - текущие данные провайдера, при перезагрузке подменяются атомарно целиком
+	Source struct {
+		provider Provider
+		interval time.Duration
+
+		data atomic.Value // map[string][]string
+
+		mu    sync.Mutex
+		stats Stats
+	}
+)
+
+// NewSource - первая загрузка обязательна, ошибка - источник не создается
+func NewSource(provider Provider, interval time.Duration) (*Source, error) {
+	if interval <= 0 {
+		interval = ReloadIntervalDefault
+	}
+
+	s := &Source{
+		provider: provider,
+		interval: interval,
+		stats:    Stats{Provider: provider.Name()},
+	}
+	s.data.Store(map[string][]string{})
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Lookup - строки для домена (punycode, без учета регистра)
+func (s *Source) Lookup(fqdn string) ([]string, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	lines, found := s.data.Load().(map[string][]string)[normalizeDomain(fqdn)]
+	return lines, found
+}
+
+// Reload - загрузить данные провайдера. При ошибке остаются данные предыдущей загрузки.
+func (s *Source) Reload() error {
+	started := time.Now()
+	loaded, err := s.provider.Load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Loads++
+	if err != nil {
+		s.stats.Failures++
+		s.stats.Error = err.Error()
+		return errors.WithMessagef(err, "can't load %s", s.provider.Name())
+	}
+
+	data := make(map[string][]string, len(loaded))
+	lines := 0
+	for domain, l := range loaded {
+		key := normalizeDomain(domain)
+		data[key] = append(data[key], l...)
+		lines += len(l)
+	}
+	s.data.Store(data)
+
+	s.stats.LoadedAt = time.Now()
+	s.stats.Domains = len(data)
+	s.stats.Lines = lines
+	s.stats.Duration = time.Since(started)
+	s.stats.Error = ""
+
+	return nil
+}
+
+func (s *Source) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Run - перезагрузка раз в interval (файл - только при изменении), report получает результат каждой попытки
+func (s *Source) Run(stop <-chan struct{}, report func(stats Stats, err error)) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if cd, ok := s.provider.(changeDetector); ok {
+			changed, err := cd.Changed()
+			if err == nil && !changed {
+				continue
+			}
+		}
+
+		err := s.Reload()
+		if report != nil {
+			report(s.Stats(), err)
+		}
+	}
+}
+
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		return ascii
+	}
+	return domain
+}
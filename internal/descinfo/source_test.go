@@ -0,0 +1,136 @@
+package descinfo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-descinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"desc.csv":  "# domain,line\nExample.RU,descr:         first\nexample.ru, descr:         second\nокна.рф,descr:         idn\n",
+		"desc.json": `{"example.ru": ["descr:         first", "descr:         second"], "окна.рф": ["descr:         idn"]}`,
+		"desc.yaml": "example.ru:\n- 'descr:         first'\n- 'descr:         second'\nокна.рф:\n- 'descr:         idn'\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		_ = ioutil.WriteFile(path, []byte(content), 0644)
+
+		p, err := NewFileProvider(path)
+		if err != nil {
+			t.Fatalf("provider for %s not created: %v", name, err)
+		}
+
+		s, err := NewSource(p, time.Hour)
+		if err != nil {
+			t.Fatalf("source for %s not created: %v", name, err)
+		}
+
+		if lines, found := s.Lookup("EXAMPLE.ru."); !found ||
+			!reflect.DeepEqual(lines, []string{"descr:         first", "descr:         second"}) {
+			t.Errorf("unexpected lines from %s: %v", name, lines)
+		}
+		if _, found := s.Lookup("xn--80atjc.xn--p1ai"); !found {
+			t.Errorf("idn domain from %s not found", name)
+		}
+		if stats := s.Stats(); stats.Domains != 2 || stats.Lines != 3 || stats.Loads != 1 || stats.Error != "" {
+			t.Errorf("unexpected stats for %s: %+v", name, stats)
+		}
+
+		if changed, err := p.Changed(); changed || err != nil {
+			t.Errorf("unchanged file %s detected as changed: %v", name, err)
+		}
+	}
+
+	// битый файл - ошибка, данные остаются от предыдущей загрузки
+	path := filepath.Join(dir, "desc.json")
+	p, _ := NewFileProvider(path)
+	s, _ := NewSource(p, time.Hour)
+
+	_ = ioutil.WriteFile(path, []byte("{broken"), 0644)
+	if changed, _ := p.Changed(); !changed {
+		t.Errorf("file change not detected")
+	}
+	if err := s.Reload(); err == nil {
+		t.Errorf("no error for broken file")
+	}
+	if _, found := s.Lookup("example.ru"); !found {
+		t.Errorf("data lost after failed reload")
+	}
+	if stats := s.Stats(); stats.Failures != 1 || stats.Error == "" {
+		t.Errorf("unexpected stats after failed reload: %+v", stats)
+	}
+
+	if _, err := NewFileProvider(filepath.Join(dir, "desc.txt")); err == nil {
+		t.Errorf("no error for unsupported format")
+	}
+	if _, err := NewSource(&FileProvider{path: filepath.Join(dir, "missing.csv")}, 0); err == nil {
+		t.Errorf("no error for missing file")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var requests, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		rw.Header().Set("ETag", `"v1"`)
+		_, _ = rw.Write([]byte(`{"example.ru": ["descr:         billing"]}`))
+	}))
+	defer srv.Close()
+
+	s, err := NewSource(NewHTTPProvider(srv.URL, "token", time.Second), time.Millisecond*10)
+	if err != nil {
+		t.Fatalf("source not created: %v", err)
+	}
+
+	stop := make(chan struct{})
+	reloaded := make(chan Stats, 10)
+	go s.Run(stop, func(stats Stats, err error) {
+		if err == nil {
+			reloaded <- stats
+		}
+	})
+
+	select {
+	case stats := <-reloaded:
+		if stats.Loads < 2 || stats.Domains != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("source not reloaded")
+	}
+	close(stop)
+
+	if lines, found := s.Lookup("example.ru"); !found || lines[0] != "descr:         billing" {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+	if atomic.LoadInt32(&notModified) == 0 {
+		t.Errorf("etag not used")
+	}
+
+	if _, err := NewSource(NewHTTPProvider(srv.URL, "wrong", time.Second), 0); err == nil {
+		t.Errorf("no error for unauthorized request")
+	}
+}
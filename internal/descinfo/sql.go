@@ -0,0 +1,61 @@
+package descinfo
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SQLiteDriver = "sqlite3"
+	QueryDefault = "SELECT domain, line FROM whois_desc_info"
+)
+
+// SQLProvider - строки (domain, line) SQL запросом через database/sql.
+// Драйвер SQLite подключается сборкой с тегом sqlite (go build -tags sqlite).
+type SQLProvider struct {
+	driver string
+	dsn    string
+	query  string
+}
+
+func NewSQLProvider(driver, dsn, query string) *SQLProvider {
+	if query == "" {
+		query = QueryDefault
+	}
+	return &SQLProvider{driver: driver, dsn: dsn, query: query}
+}
+
+// NewSQLiteProvider - файл SQLite, открывается только на чтение
+func NewSQLiteProvider(path, query string) *SQLProvider {
+	return NewSQLProvider(SQLiteDriver, "file:"+path+"?mode=ro", query)
+}
+
+func (p *SQLProvider) Name() string {
+	return p.driver + " " + p.dsn
+}
+
+func (p *SQLProvider) Load() (map[string][]string, error) {
+	db, err := sql.Open(p.driver, p.dsn)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't open %s database (is the binary built with -tags sqlite?)", p.driver)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(p.query)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query failed")
+	}
+	defer rows.Close()
+
+	data := map[string][]string{}
+	for rows.Next() {
+		var domain, line string
+		if err := rows.Scan(&domain, &line); err != nil {
+			return nil, errors.WithMessage(err, "can't scan row")
+		}
+		data[domain] = append(data[domain], line)
+	}
+
+	return data, errors.WithMessage(rows.Err(), "rows iteration failed")
+}
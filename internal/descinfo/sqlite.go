@@ -0,0 +1,11 @@
+//go:build sqlite
+// +build sqlite
+
+package descinfo
+
+import (
+	_ "github.com/mattn/go-sqlite3" // SQLiteDriver
+)
+
+// SQLiteSupported - драйвер SQLite включен в сборку
+const SQLiteSupported = true
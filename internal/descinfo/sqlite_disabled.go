@@ -0,0 +1,7 @@
+//go:build !sqlite
+// +build !sqlite
+
+package descinfo
+
+// SQLiteSupported - драйвер SQLite включен в сборку (go build -tags sqlite, нужен cgo)
+const SQLiteSupported = false
@@ -0,0 +1,49 @@
+//go:build sqlite
+// +build sqlite
+
+package descinfo
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-descinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "billing.db")
+	db, err := sql.Open(SQLiteDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"CREATE TABLE whois_desc_info (domain TEXT, line TEXT)",
+		"INSERT INTO whois_desc_info VALUES ('example.ru', 'descr:         first'), ('example.ru', 'descr:         second')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("can't prepare database: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	s, err := NewSource(NewSQLiteProvider(path, ""), time.Hour)
+	if err != nil {
+		t.Fatalf("source not created: %v", err)
+	}
+
+	if lines, found := s.Lookup("example.ru"); !found || len(lines) != 2 {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+
+	if _, err := NewSource(NewSQLiteProvider(path, "SELECT nothing FROM missing"), time.Hour); err == nil {
+		t.Errorf("no error for bad query")
+	}
+}
@@ -0,0 +1,104 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxDiffCells - предел размера таблицы LCS (строк одной версии × строк другой без общих начала и конца):
+// таблица int, больше - ErrDiffTooLarge, чтобы большие версии не занимали сотни MB на запрос
+const MaxDiffCells = 1 << 20
+
+var ErrDiffTooLarge = errors.New("versions are too large to diff")
+
+// Diff - построчная разница двух версий: "- " удаленные строки, "+ " добавленные, "  " общие
+func Diff(from, to Version) (string, error) {
+	a := splitLines(from.Raw)
+	b := splitLines(to.Raw)
+
+	// общие начало и конец не участвуют в LCS
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if (len(ma)+1)*(len(mb)+1) > MaxDiffCells {
+		return "", errors.WithMessagef(ErrDiffTooLarge, "%d and %d changed lines", len(ma), len(mb))
+	}
+
+	// lcs[i][j] - длина наибольшей общей подпоследовательности ma[i:] и mb[j:]
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + from.FirstSeen.UTC().Format(time.RFC3339) + "\n")
+	sb.WriteString("+++ " + to.FirstSeen.UTC().Format(time.RFC3339) + "\n")
+
+	for _, line := range a[:prefix] {
+		sb.WriteString("  " + line + "\n")
+	}
+
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			sb.WriteString("  " + ma[i] + "\n")
+			i++
+			j++
+		case j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + ma[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + mb[j] + "\n")
+			j++
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		sb.WriteString("  " + line + "\n")
+	}
+
+	return sb.String(), nil
+}
+
+func splitLines(raw string) []string {
+	raw = strings.TrimRight(strings.Replace(raw, "\r\n", "\n", -1), "\n")
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, "\n")
+}
+
+// ParseTime - момент для запроса истории: RFC3339 или дата 2006-01-02 (состояние на конец дня UTC)
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return day.Add(24*time.Hour - time.Nanosecond), nil
+}
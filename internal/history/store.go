@@ -0,0 +1,235 @@
+package history
+
+import (
+	"crypto/sha1" //nolint:gosec // используется только для имени файла и сравнения версий
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const recordExt = ".json"
+
+// volatileLines - строки, меняющиеся в каждом ответе (время ответа/обновления базы whois),
+// не учитываются при сравнении версий
+var volatileLines = regexp.MustCompile(`(?mi)^(>>> Last update of whois database:.*<<<|Last updated on .*)\r?\n?`)
+
+type (
+	// Version - одна из различающихся версий whois ответа по ключу
+	Version struct {
+		Raw       string    `json:"raw"`
+		Hash      string    `json:"hash"`
+		FirstSeen time.Time `json:"firstSeen"`
+		LastSeen  time.Time `json:"lastSeen"`
+		Upstream  string    `json:"upstream,omitempty"`
+	}
+
+	// Store - встроенное хранилище истории на диске: один файл на ключ со списком версий
+	// (по возрастанию FirstSeen). Запись атомарная (tmp файл + rename).
+	Store struct {
+		dir       string
+		retention time.Duration // 0 - хранить всегда
+
+		mu sync.Mutex
+	}
+
+	record struct {
+		Key      string    `json:"key"`
+		Versions []Version `json:"versions"`
+	}
+)
+
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if dir == "" {
+		return nil, errors.New("history path is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WithMessagef(err, "can't create history dir %s", dir)
+	}
+
+	return &Store{dir: dir, retention: retention}, nil
+}
+
+// Record - учесть ответ upstream на момент at. true - ответ отличается от последней версии
+// и записан новой версией, иначе у последней версии обновляется LastSeen.
+func (s *Store) Record(key, raw, upstream string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.load(key)
+	if err != nil {
+		return false, err
+	}
+
+	hash := contentHash(raw)
+	if n := len(rec.Versions); n > 0 && rec.Versions[n-1].Hash == hash {
+		last := &rec.Versions[n-1]
+		if !at.After(last.LastSeen) {
+			return false, nil
+		}
+		last.LastSeen = at
+		return false, s.save(rec)
+	}
+
+	rec.Versions = append(rec.Versions, Version{
+		Raw:       raw,
+		Hash:      hash,
+		FirstSeen: at,
+		LastSeen:  at,
+		Upstream:  upstream,
+	})
+
+	return true, s.save(rec)
+}
+
+// Versions - все сохраненные версии ключа по возрастанию FirstSeen
+func (s *Store) Versions(key string) ([]Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.load(key)
+	return rec.Versions, err
+}
+
+// At - версия, действовавшая на момент t (последняя с FirstSeen <= t)
+func (s *Store) At(key string, t time.Time) (Version, bool, error) {
+	versions, err := s.Versions(key)
+	if err != nil {
+		return Version{}, false, err
+	}
+
+	i := sort.Search(len(versions), func(i int) bool {
+		return versions[i].FirstSeen.After(t)
+	})
+	if i == 0 {
+		return Version{}, false, nil
+	}
+
+	return versions[i-1], true, nil
+}
+
+// Prune - удалить версии, не встречавшиеся дольше retention. Возвращает число удаленных версий.
+func (s *Store) Prune(now time.Time) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := now.Add(-s.retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "can't read history dir %s", s.dir)
+	}
+
+	pruned := 0
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), recordExt) {
+			continue
+		}
+		path := filepath.Join(s.dir, f.Name())
+
+		rec, err := readRecord(path)
+		if err != nil {
+			continue // битые файлы не трогаем
+		}
+
+		kept := rec.Versions[:0]
+		for _, v := range rec.Versions {
+			if v.LastSeen.Before(cutoff) {
+				pruned++
+				continue
+			}
+			kept = append(kept, v)
+		}
+
+		switch {
+		case len(kept) == len(rec.Versions):
+		case len(kept) == 0:
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return pruned, errors.WithMessagef(err, "can't remove history of %s", rec.Key)
+			}
+		default:
+			rec.Versions = kept
+			if err := s.save(rec); err != nil {
+				return pruned, err
+			}
+		}
+	}
+
+	return pruned, nil
+}
+
+func (s *Store) path(key string) string {
+	sum := sha1.Sum([]byte(key)) //nolint:gosec
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+recordExt)
+}
+
+func (s *Store) load(key string) (record, error) {
+	rec, err := readRecord(s.path(key))
+	if os.IsNotExist(errors.Cause(err)) {
+		return record{Key: key}, nil
+	}
+	if err != nil {
+		return record{Key: key}, errors.WithMessagef(err, "can't read history of %s", key)
+	}
+	return rec, nil
+}
+
+func (s *Store) save(rec record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return errors.WithMessagef(err, "can't encode history of %s", rec.Key)
+	}
+
+	path := s.path(rec.Key)
+	tmp, err := ioutil.TempFile(s.dir, filepath.Base(path)+".tmp-")
+	if err != nil {
+		return errors.WithMessage(err, "can't create history tmp file")
+	}
+
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WithMessagef(err, "can't save history of %s", rec.Key)
+	}
+
+	return nil
+}
+
+func readRecord(path string) (record, error) {
+	var rec record
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+
+	err = json.Unmarshal(b, &rec)
+	return rec, err
+}
+
+// contentHash - хэш ответа без volatileLines
+func contentHash(raw string) string {
+	sum := sha1.Sum([]byte(volatileLines.ReplaceAllString(raw, ""))) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
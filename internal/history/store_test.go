@@ -0,0 +1,174 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func newTestStore(t *testing.T, retention time.Duration) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "whois-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(dir, retention)
+	if err != nil {
+		t.Fatalf("history store not opened: %v", err)
+	}
+
+	return s, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestStore_RecordAndAt(t *testing.T) {
+	s, cleanup := newTestStore(t, 0)
+	defer cleanup()
+
+	day := func(d int) time.Time { return time.Date(2026, 9, d, 12, 0, 0, 0, time.UTC) }
+
+	steps := []struct {
+		raw     string
+		at      time.Time
+		created bool
+	}{
+		{"registrar: A\n", day(1), true},
+		{"registrar: A\n", day(3), false},
+		{"registrar: B\n", day(5), true},
+		{"registrar: A\n", day(7), true},
+	}
+	for n, step := range steps {
+		created, err := s.Record("example.ru", step.raw, "whois.tcinet.ru:43", step.at)
+		if err != nil || created != step.created {
+			t.Fatalf("unexpected record result for step #%d: %v %v", n, created, err)
+		}
+	}
+
+	versions, _ := s.Versions("example.ru")
+	if len(versions) != 3 || !versions[0].LastSeen.Equal(day(3)) || versions[1].Upstream != "whois.tcinet.ru:43" {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+
+	testCases := []struct {
+		at    time.Time
+		found bool
+		raw   string
+	}{
+		{day(1).Add(-time.Second), false, ""},
+		{day(1), true, "registrar: A\n"},
+		{day(4), true, "registrar: A\n"},
+		{day(6), true, "registrar: B\n"},
+		{day(30), true, "registrar: A\n"},
+	}
+	for n, test := range testCases {
+		v, found, err := s.At("example.ru", test.at)
+		if err != nil || found != test.found || v.Raw != test.raw {
+			t.Errorf("unexpected result for test case #%d: %+v %v %v", n, v, found, err)
+		}
+	}
+
+	if _, found, err := s.At("unknown.ru", day(30)); found || err != nil {
+		t.Errorf("found history of unknown key: %v", err)
+	}
+}
+
+func TestStore_Record_VolatileLines(t *testing.T) {
+	s, cleanup := newTestStore(t, 0)
+	defer cleanup()
+
+	at := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	answers := []string{
+		"   Domain Name: EXAMPLE.COM\r\n>>> Last update of whois database: 2026-10-19T10:00:00Z <<<\r\n",
+		"   Domain Name: EXAMPLE.COM\r\n>>> Last update of whois database: 2026-10-19T10:05:00Z <<<\r\n",
+		"domain:        EXAMPLE.RU\nLast updated on 2026-10-19T10:05:00Z\n",
+		"domain:        EXAMPLE.RU\nLast updated on 2026-10-19T10:10:00Z\n",
+	}
+	for n, raw := range answers {
+		created, err := s.Record("example", raw, "", at.Add(time.Duration(n)*time.Minute))
+		if err != nil || created != (n%2 == 0) {
+			t.Errorf("unexpected record result for answer #%d: %v %v", n, created, err)
+		}
+	}
+
+	if versions, _ := s.Versions("example"); len(versions) != 2 {
+		t.Errorf("unexpected versions: %+v", versions)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	s, cleanup := newTestStore(t, 24*time.Hour)
+	defer cleanup()
+
+	now := time.Now()
+	_, _ = s.Record("old.ru", "v1", "", now.Add(-72*time.Hour))
+	_, _ = s.Record("mixed.ru", "v1", "", now.Add(-48*time.Hour))
+	_, _ = s.Record("mixed.ru", "v2", "", now.Add(-time.Hour))
+
+	pruned, err := s.Prune(now)
+	if err != nil || pruned != 2 {
+		t.Fatalf("unexpected prune result: %d %v", pruned, err)
+	}
+
+	if versions, _ := s.Versions("old.ru"); len(versions) != 0 {
+		t.Errorf("old history not pruned: %+v", versions)
+	}
+	if versions, _ := s.Versions("mixed.ru"); len(versions) != 1 || versions[0].Raw != "v2" {
+		t.Errorf("unexpected history after prune: %+v", versions)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := Version{Raw: "domain: example.ru\r\nregistrar: A\r\nstate: DELEGATED\r\n"}
+	to := Version{Raw: "domain: example.ru\r\nregistrar: B\r\nstate: DELEGATED\r\n"}
+
+	expected := "--- 0001-01-01T00:00:00Z\n+++ 0001-01-01T00:00:00Z\n" +
+		"  domain: example.ru\n- registrar: A\n+ registrar: B\n  state: DELEGATED\n"
+	if diff, err := Diff(from, to); err != nil || diff != expected {
+		t.Errorf("unexpected diff:\n%s %v", diff, err)
+	}
+
+	// общие начало и конец не ограничены размером, только измененная середина
+	common := strings.Repeat("remarks: same\n", 5000)
+	from = Version{Raw: common + "registrar: A\n" + common}
+	to = Version{Raw: common + "registrar: B\n" + common}
+	if diff, err := Diff(from, to); err != nil || !strings.Contains(diff, "- registrar: A\n+ registrar: B\n") {
+		t.Errorf("unexpected diff of large versions with small change: %v", err)
+	}
+
+	from = Version{Raw: lines("a", 2000)}
+	to = Version{Raw: lines("b", 2000)}
+	if _, err := Diff(from, to); errors.Cause(err) != ErrDiffTooLarge {
+		t.Errorf("unexpected error for oversized diff: %v", err)
+	}
+}
+
+func lines(prefix string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString(prefix + ": " + strconv.Itoa(i) + "\n")
+	}
+	return sb.String()
+}
+
+func TestParseTime(t *testing.T) {
+	testCases := []struct {
+		s        string
+		expected time.Time
+		err      bool
+	}{
+		{"2026-09-01", time.Date(2026, 9, 1, 23, 59, 59, 999999999, time.UTC), false},
+		{"2026-09-01T10:00:00Z", time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for n, test := range testCases {
+		got, err := ParseTime(test.s)
+		if (err != nil) != test.err || !got.Equal(test.expected) {
+			t.Errorf("unexpected result for test case #%d: %v %v", n, got, err)
+		}
+	}
+}
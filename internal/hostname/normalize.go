@@ -0,0 +1,53 @@
+package hostname
+
+import (
+	"strings"
+)
+
+// Normalize - домен из того, что пользователи присылают вместо домена:
+//
+//	https://www.Example.com:443/path?q#f -> example.com
+//	user@example.com                     -> example.com
+//	example.com.                         -> example.com
+//
+// "www." отбрасывается, если после него остается больше одной метки (www.ru не меняется), keepWWW - не отбрасывать.
+func Normalize(query string, keepWWW bool) string {
+	q := strings.TrimSpace(query)
+
+	// схема URL
+	if i := strings.Index(q, "://"); i >= 0 {
+		q = q[i+3:]
+	}
+
+	// путь, параметры и фрагмент URL
+	if i := strings.IndexAny(q, "/?#"); i >= 0 {
+		q = q[:i]
+	}
+
+	// e-mail, mailto: и userinfo URL - домен после последнего "@"
+	if i := strings.LastIndex(q, "@"); i >= 0 {
+		q = q[i+1:]
+	}
+
+	// порт
+	if i := strings.LastIndex(q, ":"); i >= 0 && isDigits(q[i+1:]) {
+		q = q[:i]
+	}
+
+	q = strings.ToLower(strings.TrimRight(q, "."))
+
+	if !keepWWW && strings.HasPrefix(q, "www.") && strings.Count(q, ".") > 1 {
+		q = q[len("www."):]
+	}
+
+	return q
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
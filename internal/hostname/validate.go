@@ -0,0 +1,104 @@
+package hostname
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MaxLengthDefault      = 253 // RFC 1035, без завершающей точки
+	MaxLabelLengthDefault = 63
+	MinTLDLengthDefault   = 2
+
+	acePrefix = "xn--"
+)
+
+// Rules - правила проверки домена в ASCII форме (A-label), нулевые значения - значения по умолчанию
+type Rules struct {
+	MaxLength      int
+	MaxLabelLength int
+	MinTLDLength   int
+
+	AllowUnderscore   bool // "_" в метках, кроме зоны
+	AllowLooseHyphens bool // "-" в начале и конце метки, "--" в 3-4 позиции без "xn--"
+}
+
+// Validate - проверка домена (после Normalize и перевода в punycode), ошибка описывает причину отказа
+func (r Rules) Validate(domain string) error {
+	if domain == "" {
+		return errors.New("domain is empty")
+	}
+
+	if l := len(domain); l > orDefault(r.MaxLength, MaxLengthDefault) {
+		return errors.Errorf("domain is too long: %d > %d characters", l, orDefault(r.MaxLength, MaxLengthDefault))
+	}
+
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if err := r.validateLabel(label, i == len(labels)-1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r Rules) validateLabel(label string, tld bool) error {
+	switch {
+	case label == "":
+		return errors.New("empty label")
+	case len(label) > orDefault(r.MaxLabelLength, MaxLabelLengthDefault):
+		return errors.Errorf("label %q is too long: %d > %d characters", label, len(label), orDefault(r.MaxLabelLength, MaxLabelLengthDefault))
+	}
+
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-':
+		case c == '_' && r.AllowUnderscore && !tld:
+		default:
+			return errors.Errorf("label %q contains invalid character %q", label, c)
+		}
+	}
+
+	if !r.AllowLooseHyphens {
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return errors.Errorf("label %q starts or ends with a hyphen", label)
+		}
+		if len(label) >= 4 && label[2:4] == "--" && !strings.HasPrefix(label, acePrefix) {
+			return errors.Errorf("label %q has hyphens in the 3rd and 4th positions", label)
+		}
+	}
+
+	if tld {
+		return r.validateTLD(label)
+	}
+
+	return nil
+}
+
+// validateTLD - зона из букв или IDN зона (xn--...)
+func (r Rules) validateTLD(label string) error {
+	if strings.HasPrefix(label, acePrefix) {
+		return nil
+	}
+
+	if len(label) < orDefault(r.MinTLDLength, MinTLDLengthDefault) {
+		return errors.Errorf("tld %q is too short", label)
+	}
+
+	for _, c := range label {
+		if c < 'a' || c > 'z' {
+			return errors.Errorf("tld %q must contain only letters", label)
+		}
+	}
+
+	return nil
+}
+
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,122 @@
+package invalidate
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MaxSkewDefault = 30 * time.Second
+)
+
+var (
+	ErrBadSignature = errors.New("bad message signature")
+	ErrStale        = errors.New("stale message")
+)
+
+type (
+	// Message - команда удалить ключ из кэша на всех репликах
+	Message struct {
+		ID        string `json:"id"`
+		Key       string `json:"key"`
+		Origin    string `json:"origin"`
+		Time      int64  `json:"time"` // unix nano
+		Signature string `json:"signature"`
+	}
+
+	// Bus - подпись (HMAC-SHA256 общим секретом), проверка и дедупликация сообщений
+	Bus struct {
+		secret  []byte
+		origin  string
+		maxSkew time.Duration
+
+		mu   sync.Mutex
+		seen map[string]time.Time // id -> время получения
+	}
+)
+
+func NewBus(secret, origin string, maxSkew time.Duration) (*Bus, error) {
+	if secret == "" {
+		return nil, errors.New("invalidation secret is empty")
+	}
+
+	if maxSkew <= 0 {
+		maxSkew = MaxSkewDefault
+	}
+
+	return &Bus{
+		secret:  []byte(secret),
+		origin:  origin,
+		maxSkew: maxSkew,
+		seen:    map[string]time.Time{},
+	}, nil
+}
+
+// New - новое подписанное сообщение этой реплики
+func (b *Bus) New(key string) Message {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	msg := Message{
+		ID:     hex.EncodeToString(id),
+		Key:    key,
+		Origin: b.origin,
+		Time:   time.Now().UnixNano(),
+	}
+	msg.Signature = b.sign(msg)
+
+	b.markSeen(msg.ID, time.Now())
+
+	return msg
+}
+
+// Accept - проверить сообщение. false без ошибки - сообщение уже обработано (или свое).
+func (b *Bus) Accept(msg Message) (bool, error) {
+	if !hmac.Equal([]byte(msg.Signature), []byte(b.sign(msg))) {
+		return false, ErrBadSignature
+	}
+
+	now := time.Now()
+	if skew := now.Sub(time.Unix(0, msg.Time)); skew > b.maxSkew || skew < -b.maxSkew {
+		return false, ErrStale
+	}
+
+	if msg.Origin == b.origin {
+		return false, nil
+	}
+
+	return b.markSeen(msg.ID, now), nil
+}
+
+func (b *Bus) sign(msg Message) string {
+	mac := hmac.New(sha256.New, b.secret)
+	_, _ = mac.Write([]byte(msg.ID + "\n" + msg.Key + "\n" + msg.Origin + "\n" + strconv.FormatInt(msg.Time, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// markSeen - запомнить id, false - уже видели. Старые id (старше 2*maxSkew) забываются,
+// т.к. такие сообщения все равно отклоняются как stale.
+func (b *Bus) markSeen(id string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for seenID, t := range b.seen {
+		if now.Sub(t) > 2*b.maxSkew {
+			delete(b.seen, seenID)
+		}
+	}
+
+	if _, found := b.seen[id]; found {
+		return false
+	}
+	b.seen[id] = now
+
+	return true
+}
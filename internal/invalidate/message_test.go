@@ -0,0 +1,109 @@
+package invalidate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBus_Accept(t *testing.T) {
+	a, _ := NewBus("secret", "a", time.Minute)
+	b, _ := NewBus("secret", "b", time.Minute)
+	other, _ := NewBus("other secret", "c", time.Minute)
+
+	msg := a.New("test.domain.ru")
+
+	if accepted, err := b.Accept(msg); !accepted || err != nil {
+		t.Fatalf("message not accepted: %v", err)
+	}
+
+	// повтор сообщения игнорируется
+	if accepted, err := b.Accept(msg); accepted || err != nil {
+		t.Errorf("duplicate message accepted: %v", err)
+	}
+
+	// свое сообщение игнорируется
+	if accepted, err := a.Accept(msg); accepted || err != nil {
+		t.Errorf("own message accepted: %v", err)
+	}
+
+	if _, err := other.Accept(msg); errors.Cause(err) != ErrBadSignature {
+		t.Errorf("message with foreign secret: %v", err)
+	}
+
+	forged := a.New("test.domain.ru")
+	forged.Key = "other.domain.ru"
+	if _, err := b.Accept(forged); errors.Cause(err) != ErrBadSignature {
+		t.Errorf("forged message: %v", err)
+	}
+
+	stale := Message{ID: "1", Key: "test.domain.ru", Origin: "a", Time: time.Now().Add(-time.Hour).UnixNano()}
+	stale.Signature = a.sign(stale)
+	if _, err := b.Accept(stale); errors.Cause(err) != ErrStale {
+		t.Errorf("stale message: %v", err)
+	}
+
+	if _, err := NewBus("", "a", 0); err == nil {
+		t.Errorf("bus with empty secret created")
+	}
+}
+
+func TestHTTPPeers_Publish(t *testing.T) {
+	a, _ := NewBus("secret", "a", time.Minute)
+	b, _ := NewBus("secret", "b", time.Minute)
+
+	received := make(chan Message, 1)
+	srv := httptest.NewServer(Handler(func(msg Message) error {
+		if _, err := b.Accept(msg); err != nil {
+			return err
+		}
+		received <- msg
+		return nil
+	}))
+	defer srv.Close()
+
+	peers := NewHTTPPeers([]string{srv.URL}, time.Second)
+	if err := peers.Publish(a.New("test.domain.ru")); err != nil {
+		t.Fatalf("can't publish message: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Key != "test.domain.ru" || msg.Origin != "a" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	default:
+		t.Fatalf("message not received")
+	}
+
+	// неверная подпись - ошибка публикации
+	msg := a.New("test.domain.ru")
+	msg.Signature = "bad"
+	if err := peers.Publish(msg); err == nil {
+		t.Errorf("rejected message published without error")
+	}
+
+	resp, err := http.Get(srv.URL + HTTPPath)
+	if err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected GET result: %v %v", resp, err)
+	}
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+}
+
+func Test_nextBackoff(t *testing.T) {
+	var backoff time.Duration
+	for _, expected := range []time.Duration{listenBackoffMin, 2 * listenBackoffMin, 4 * listenBackoffMin} {
+		if backoff = nextBackoff(backoff); backoff != expected {
+			t.Errorf("unexpected backoff %s, expected %s", backoff, expected)
+		}
+	}
+
+	if backoff = nextBackoff(listenBackoffMax); backoff != listenBackoffMax {
+		t.Errorf("backoff over max: %s", backoff)
+	}
+}
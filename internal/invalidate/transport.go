@@ -0,0 +1,183 @@
+package invalidate
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	HTTPPath           = "/internal/invalidate"
+	TimeoutDefault     = 2 * time.Second
+	maxMulticastPacket = 8192
+
+	// пауза после ошибки чтения multicast, удваивается до listenBackoffMax
+	listenBackoffMin = 10 * time.Millisecond
+	listenBackoffMax = 5 * time.Second
+)
+
+// Transport - канал доставки сообщений другим репликам
+type Transport interface {
+	Publish(msg Message) error
+}
+
+// HTTPPeers - рассылка сообщений списку реплик POST запросом на HTTPPath
+type HTTPPeers struct {
+	Peers  []string // base url: http://10.0.0.2:8043
+	Client *http.Client
+}
+
+func NewHTTPPeers(peers []string, timeout time.Duration) *HTTPPeers {
+	if timeout <= 0 {
+		timeout = TimeoutDefault
+	}
+	return &HTTPPeers{Peers: peers, Client: &http.Client{Timeout: timeout}}
+}
+
+func (h *HTTPPeers) Publish(msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, peer := range h.Peers {
+		resp, err := h.Client.Post(strings.TrimRight(peer, "/")+HTTPPath, "application/json", bytes.NewReader(body))
+		if err != nil {
+			failed = append(failed, peer)
+			continue
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			failed = append(failed, peer)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("invalidation not delivered to peers: %v", failed)
+	}
+	return nil
+}
+
+// Multicast - рассылка сообщений UDP multicast в LAN
+type Multicast struct {
+	group *net.UDPAddr
+	iface *net.Interface
+}
+
+func NewMulticast(group, iface string) (*Multicast, error) {
+	addr, err := net.ResolveUDPAddr("udp", group)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "bad multicast group %s", group)
+	}
+
+	m := &Multicast{group: addr}
+	if iface != "" {
+		if m.iface, err = net.InterfaceByName(iface); err != nil {
+			return nil, errors.WithMessagef(err, "bad multicast interface %s", iface)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Multicast) Publish(msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialUDP("udp", nil, m.group)
+	if err != nil {
+		return errors.WithMessagef(err, "can't dial multicast group %s", m.group)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(body)
+	return err
+}
+
+// Listen - прием сообщений группы до закрытия stop. Ошибки чтения передаются в onError,
+// следующее чтение - после паузы (от listenBackoffMin до listenBackoffMax).
+func (m *Multicast) Listen(stop <-chan struct{}, handle func(msg Message), onError func(err error)) error {
+	conn, err := net.ListenMulticastUDP("udp", m.iface, m.group)
+	if err != nil {
+		return errors.WithMessagef(err, "can't listen multicast group %s", m.group)
+	}
+
+	go func() {
+		<-stop
+		_ = conn.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, maxMulticastPacket)
+		var backoff time.Duration
+		for {
+			n, _, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				onError(errors.WithMessagef(err, "can't read multicast group %s", m.group))
+				backoff = nextBackoff(backoff)
+				select {
+				case <-stop:
+					return
+				case <-time.After(backoff):
+				}
+				continue
+			}
+			backoff = 0
+
+			var msg Message
+			if json.Unmarshal(buf[:n], &msg) == nil {
+				handle(msg)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff < listenBackoffMin {
+		return listenBackoffMin
+	}
+	if backoff *= 2; backoff > listenBackoffMax {
+		return listenBackoffMax
+	}
+	return backoff
+}
+
+// Handler - прием сообщений от HTTPPeers
+func Handler(handle func(msg Message) error) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var msg Message
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxMulticastPacket)).Decode(&msg); err != nil {
+			http.Error(rw, "bad message", http.StatusBadRequest)
+			return
+		}
+
+		if err := handle(msg); err != nil {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+	}
+}
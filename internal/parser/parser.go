@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record - разобранные поля whois ответа, важные для отслеживания изменений
+type Record struct {
+	Domain      string    `json:"domain,omitempty"`
+	Registrar   string    `json:"registrar,omitempty"`
+	Nameservers []string  `json:"nameservers,omitempty"` // lower case, без завершающей точки, отсортированы
+	Status      []string  `json:"status,omitempty"`      // lower case, отсортированы
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+type field int
+
+const (
+	fieldDomain field = iota
+	fieldRegistrar
+	fieldNameserver
+	fieldStatus
+	fieldCreated
+	fieldExpires
+)
+
+// fields - имена полей разных реестров (tcinet .ru/.рф/.su, Verisign .com/.net, ICANN формат)
+var fields = map[string]field{
+	"domain":      fieldDomain,
+	"domain name": fieldDomain,
+
+	"registrar": fieldRegistrar,
+
+	"nserver":     fieldNameserver,
+	"name server": fieldNameserver,
+	"nameserver":  fieldNameserver,
+	"nameservers": fieldNameserver,
+
+	"state":         fieldStatus,
+	"status":        fieldStatus,
+	"domain status": fieldStatus,
+
+	"created":       fieldCreated,
+	"creation date": fieldCreated,
+
+	"paid-till":                              fieldExpires,
+	"registry expiry date":                   fieldExpires,
+	"registrar registration expiration date": fieldExpires,
+	"expiration date":                        fieldExpires,
+	"expires":                                fieldExpires,
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006.01.02",
+	"02-Jan-2006",
+}
+
+// Parse - разбор "key: value" строк whois ответа. Неизвестные поля и строки комментариев пропускаются.
+func Parse(raw string) Record {
+	var rec Record
+
+	for _, line := range strings.Split(strings.Replace(raw, "\r\n", "\n", -1), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '%' || line[0] == '#' || strings.HasPrefix(line, ">>>") {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			continue
+		}
+
+		f, known := fields[strings.ToLower(strings.TrimSpace(line[:i]))]
+		value := strings.TrimSpace(line[i+1:])
+		if !known || value == "" {
+			continue
+		}
+
+		switch f {
+		case fieldDomain:
+			if rec.Domain == "" {
+				rec.Domain = strings.ToLower(strings.TrimSuffix(value, "."))
+			}
+		case fieldRegistrar:
+			if rec.Registrar == "" {
+				rec.Registrar = value
+			}
+		case fieldNameserver:
+			// "ns1.example.ru. 192.0.2.1" - адреса glue записей не учитываются
+			ns := strings.ToLower(strings.TrimSuffix(strings.Fields(value)[0], "."))
+			rec.Nameservers = appendUnique(rec.Nameservers, ns)
+		case fieldStatus:
+			// "REGISTERED, DELEGATED, VERIFIED" (tcinet), "clientTransferProhibited https://icann.org/epp#..." (ICANN)
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					rec.Status = appendUnique(rec.Status, strings.ToLower(strings.Fields(s)[0]))
+				}
+			}
+		case fieldCreated:
+			if t, ok := parseTime(value); ok && rec.Created.IsZero() {
+				rec.Created = t
+			}
+		case fieldExpires:
+			if t, ok := parseTime(value); ok && rec.Expires.IsZero() {
+				rec.Expires = t
+			}
+		}
+	}
+
+	sort.Strings(rec.Nameservers)
+	sort.Strings(rec.Status)
+
+	return rec
+}
+
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		expected Record
+	}{
+		{
+			name: "tcinet",
+			raw: "% TCI Whois Service. Terms of use:\r\n" +
+				"% https://tcinet.ru/documents/whois_ru_rf.pdf\r\n\r\n" +
+				"domain:        EXAMPLE.RU\r\n" +
+				"nserver:       ns2.example.ru. 192.0.2.2\r\n" +
+				"nserver:       ns1.example.ru.\r\n" +
+				"state:         REGISTERED, DELEGATED, VERIFIED\r\n" +
+				"org:           Example LLC\r\n" +
+				"registrar:     RU-CENTER-RU\r\n" +
+				"created:       2005-04-01T20:00:00Z\r\n" +
+				"paid-till:     2027-04-01T21:00:00Z\r\n" +
+				"source:        TCI\r\n",
+			expected: Record{
+				Domain:      "example.ru",
+				Registrar:   "RU-CENTER-RU",
+				Nameservers: []string{"ns1.example.ru", "ns2.example.ru"},
+				Status:      []string{"delegated", "registered", "verified"},
+				Created:     time.Date(2005, 4, 1, 20, 0, 0, 0, time.UTC),
+				Expires:     time.Date(2027, 4, 1, 21, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "verisign",
+			raw: "   Domain Name: EXAMPLE.COM\r\n" +
+				"   Registrar: RESERVED-Internet Assigned Numbers Authority\r\n" +
+				"   Creation Date: 1995-08-14T04:00:00Z\r\n" +
+				"   Registry Expiry Date: 2027-08-13T04:00:00Z\r\n" +
+				"   Domain Status: clientDeleteProhibited https://icann.org/epp#clientDeleteProhibited\r\n" +
+				"   Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited\r\n" +
+				"   Name Server: A.IANA-SERVERS.NET\r\n" +
+				"   Name Server: B.IANA-SERVERS.NET\r\n" +
+				">>> Last update of whois database: 2026-10-19T10:00:00Z <<<\r\n",
+			expected: Record{
+				Domain:      "example.com",
+				Registrar:   "RESERVED-Internet Assigned Numbers Authority",
+				Nameservers: []string{"a.iana-servers.net", "b.iana-servers.net"},
+				Status:      []string{"clientdeleteprohibited", "clienttransferprohibited"},
+				Created:     time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC),
+				Expires:     time.Date(2027, 8, 13, 4, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "not found",
+			raw:      "No entries found for the selected source(s).\r\n",
+			expected: Record{},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := Parse(test.raw); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("unexpected result for %s:\n%+v\n%+v", test.name, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package peer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxSkew - допустимое расхождение времени подписанного запроса
+const MaxSkew = 30 * time.Second
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrStale        = errors.New("stale request")
+)
+
+// Auth - подпись запросов и ответов протокола HMAC-SHA256 общим секретом реплик.
+// Пустой секрет - без подписи (listener протокола должен быть доступен только репликам).
+type Auth struct {
+	secret []byte
+}
+
+func NewAuth(secret string) Auth {
+	if secret == "" {
+		return Auth{}
+	}
+	return Auth{secret: []byte(secret)}
+}
+
+func (a Auth) enabled() bool {
+	return len(a.secret) > 0
+}
+
+// signRequest - аргументы FETCH ("<fqdn>" или "<fqdn> <unix nano> <подпись>") и время запроса
+func (a Auth) signRequest(fqdn string, now time.Time) (string, string) {
+	if !a.enabled() {
+		return fqdn, ""
+	}
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	return fqdn + " " + ts + " " + a.sign(cmdFetch, fqdn, ts), ts
+}
+
+// verifyRequest - fqdn и время запроса из аргументов FETCH
+func (a Auth) verifyRequest(args string, now time.Time) (string, string, error) {
+	fields := strings.Fields(args)
+	if !a.enabled() {
+		if len(fields) == 0 {
+			return "", "", errors.New("bad request")
+		}
+		return fields[0], "", nil
+	}
+
+	if len(fields) != 3 {
+		return "", "", ErrUnauthorized
+	}
+	fqdn, ts, signature := fields[0], fields[1], fields[2]
+	if !hmac.Equal([]byte(signature), []byte(a.sign(cmdFetch, fqdn, ts))) {
+		return "", "", ErrUnauthorized
+	}
+
+	nano, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", "", ErrUnauthorized
+	}
+	if skew := now.Sub(time.Unix(0, nano)); skew > MaxSkew || skew < -MaxSkew {
+		return "", "", ErrStale
+	}
+
+	return fqdn, ts, nil
+}
+
+// signReply - подпись ответа OK на запрос fqdn со временем ts: ответ нельзя подменить или взять от другого запроса
+func (a Auth) signReply(fqdn, ts string, payload []byte) string {
+	return a.sign(replyOK, fqdn, ts, string(payload))
+}
+
+// verifyReply - ответ OK ("<подпись> <json>" с секретом) на запрос fqdn со временем ts, возвращает json
+func (a Auth) verifyReply(fqdn, ts, reply string) (string, error) {
+	if !a.enabled() {
+		return reply, nil
+	}
+
+	i := strings.Index(reply, " ")
+	if i < 0 {
+		return "", ErrUnauthorized
+	}
+	signature, payload := reply[:i], reply[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(a.signReply(fqdn, ts, []byte(payload)))) {
+		return "", ErrUnauthorized
+	}
+
+	return payload, nil
+}
+
+func (a Auth) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	_, _ = mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,36 @@
+package peer
+
+import (
+	"net"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Discover - список реплик: статический список + адреса (A/AAAA) DNS имени с портом port
+func Discover(static []string, dnsName, port string) ([]string, error) {
+	uniq := map[string]bool{}
+	for _, addr := range static {
+		if addr != "" {
+			uniq[addr] = true
+		}
+	}
+
+	if dnsName != "" {
+		ips, err := net.LookupHost(dnsName)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't discover peers by %s", dnsName)
+		}
+		for _, ip := range ips {
+			uniq[net.JoinHostPort(ip, port)] = true
+		}
+	}
+
+	nodes := make([]string, 0, len(uniq))
+	for addr := range uniq {
+		nodes = append(nodes, addr)
+	}
+	sort.Strings(nodes)
+
+	return nodes, nil
+}
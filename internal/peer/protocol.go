@@ -0,0 +1,156 @@
+package peer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/server"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+// Внутренний протокол обмена кэшем между репликами (по строке на запрос и ответ):
+//
+//	-> FETCH <fqdn>\r\n
+//	<- OK <json storage.Entry>\r\n  |  ERR <message>\r\n
+//
+// С общим секретом (Auth) запрос и ответ подписаны:
+//
+//	-> FETCH <fqdn> <unix nano> <hmac>\r\n
+//	<- OK <hmac> <json storage.Entry>\r\n
+const (
+	cmdFetch   = "FETCH"
+	replyOK    = "OK"
+	replyError = "ERR"
+
+	maxRequestLen = 1 << 10 // FETCH <fqdn>
+	replyOverhead = 4 << 10 // статус и поля storage.Entry кроме Raw
+)
+
+// MaxReplyLen - предел строки ответа с записью, ответ whois сервера в которой не больше maxResponseSize байт:
+// в JSON байт ответа занимает до 6 байт ("<" -> \u003c, управляющие символы)
+func MaxReplyLen(maxResponseSize int) int {
+	return 6*maxResponseSize + replyOverhead
+}
+
+// FetchFunc - получение записи владельцем ключа (из своего кэша или от whois сервера)
+type FetchFunc = func(fqdn string) (storage.Entry, error)
+
+// Fetch - запросить запись у реплики-владельца addr. maxReplyLen - предел строки ответа (MaxReplyLen).
+func Fetch(addr, fqdn string, auth Auth, timeout time.Duration, maxReplyLen int) (storage.Entry, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return storage.Entry{}, errors.WithMessagef(err, "can't connect to peer %s", addr)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return storage.Entry{}, err
+	}
+
+	request, ts := auth.signRequest(fqdn, time.Now())
+	if _, err := fmt.Fprintf(conn, "%s %s\r\n", cmdFetch, request); err != nil {
+		return storage.Entry{}, errors.WithMessagef(err, "can't send to peer %s", addr)
+	}
+
+	line, err := readLine(bufio.NewReader(conn), maxReplyLen)
+	if err != nil {
+		return storage.Entry{}, errors.WithMessagef(err, "can't read from peer %s", addr)
+	}
+
+	status, payload := splitCommand(line)
+	switch status {
+	case replyOK:
+		if payload, err = auth.verifyReply(fqdn, ts, payload); err != nil {
+			return storage.Entry{}, errors.WithMessagef(err, "bad reply signature from peer %s", addr)
+		}
+
+		var entry storage.Entry
+		if err := json.Unmarshal([]byte(payload), &entry); err != nil {
+			return storage.Entry{}, errors.WithMessagef(err, "bad reply from peer %s", addr)
+		}
+		return entry, nil
+	case replyError:
+		return storage.Entry{}, errors.Errorf("peer %s: %s", addr, payload)
+	}
+
+	return storage.Entry{}, errors.Errorf("unknown reply from peer %s: %q", addr, status)
+}
+
+// Handler - обработчик внутреннего протокола для server.Server
+func Handler(fetch FetchFunc, auth Auth, timeout time.Duration) server.HandlerFunc {
+	return func(conn net.Conn) error {
+		defer conn.Close()
+
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+
+		line, err := readLine(bufio.NewReader(conn), maxRequestLen)
+		if err != nil {
+			return errors.WithMessage(err, "can't read peer request")
+		}
+
+		cmd, args := splitCommand(line)
+		if cmd != cmdFetch || args == "" {
+			_, err = fmt.Fprintf(conn, "%s bad request\r\n", replyError)
+			return err
+		}
+
+		fqdn, ts, err := auth.verifyRequest(args, time.Now())
+		if err != nil {
+			_, _ = fmt.Fprintf(conn, "%s %s\r\n", replyError, err)
+			return errors.WithMessagef(err, "peer request from %s", conn.RemoteAddr())
+		}
+
+		entry, err := fetch(fqdn)
+		if err != nil {
+			_, _ = fmt.Fprintf(conn, "%s %s\r\n", replyError, strings.Replace(err.Error(), "\n", " ", -1))
+			return errors.WithMessagef(err, "peer fetch of %s", fqdn)
+		}
+
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		if auth.enabled() {
+			_, err = fmt.Fprintf(conn, "%s %s %s\r\n", replyOK, auth.signReply(fqdn, ts, b), b)
+			return err
+		}
+
+		_, err = fmt.Fprintf(conn, "%s %s\r\n", replyOK, b)
+		return err
+	}
+}
+
+func readLine(r *bufio.Reader, maxLen int) (string, error) {
+	var b strings.Builder
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		b.Write(chunk)
+
+		if b.Len() > maxLen {
+			return "", errors.New("line too long")
+		}
+		if !isPrefix {
+			return b.String(), nil
+		}
+	}
+}
+
+func splitCommand(line string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
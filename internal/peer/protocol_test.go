@@ -0,0 +1,121 @@
+package peer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/server"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+// ответ whois сервера максимального размера из символов, которые JSON экранирует
+const maxResponseSize = 1 << 16
+
+var replyLen = MaxReplyLen(maxResponseSize)
+
+func TestFetch(t *testing.T) {
+	srv, err := server.New(server.TCP, "127.0.0.1", "50120", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(fqdn string) (storage.Entry, error) {
+		if fqdn == "big.ru" {
+			return storage.Entry{Raw: strings.Repeat("<\x01", maxResponseSize/2), Upstream: "whois.tcinet.ru:43"}, nil
+		}
+		if fqdn == "bad.ru" {
+			return storage.Entry{}, errors.New("upstream\nproblem")
+		}
+		return storage.Entry{Raw: "domain: " + fqdn + "\r\nsource: TCI\r\n", TTL: time.Minute, Upstream: "whois.tcinet.ru:43"}, nil
+	}
+
+	chErr := make(chan error, 10)
+	if err := srv.ListenAndServe(Handler(fetch, Auth{}, time.Second), chErr); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	// соединения до запуска воркеров пула server.Server отклоняются - повторяем
+	entry, err := Fetch(srv.Addr(), "example.ru", Auth{}, time.Second, replyLen)
+	for i := 0; err != nil && i < 100; i++ {
+		time.Sleep(5 * time.Millisecond)
+		entry, err = Fetch(srv.Addr(), "example.ru", Auth{}, time.Second, replyLen)
+	}
+	if err != nil {
+		t.Fatalf("fetch error: %v", err)
+	}
+	if entry.Raw != "domain: example.ru\r\nsource: TCI\r\n" || entry.TTL != time.Minute || entry.Upstream != "whois.tcinet.ru:43" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	if entry, err := Fetch(srv.Addr(), "big.ru", Auth{}, time.Second, replyLen); err != nil || len(entry.Raw) != maxResponseSize {
+		t.Errorf("max size entry not fetched: %v", err)
+	}
+
+	if _, err := Fetch(srv.Addr(), "bad.ru", Auth{}, time.Second, replyLen); err == nil {
+		t.Errorf("no error for failed fetch")
+	}
+
+	if _, err := Fetch("127.0.0.1:1", "example.ru", Auth{}, time.Millisecond*100, replyLen); err == nil {
+		t.Errorf("no error for unavailable peer")
+	}
+}
+
+func TestFetch_Auth(t *testing.T) {
+	srv, err := server.New(server.TCP, "127.0.0.1", "50121", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(fqdn string) (storage.Entry, error) {
+		return storage.Entry{Raw: "domain: " + fqdn + "\r\n"}, nil
+	}
+
+	chErr := make(chan error, 10)
+	if err := srv.ListenAndServe(Handler(fetch, NewAuth("secret"), time.Second), chErr); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	entry, err := Fetch(srv.Addr(), "example.ru", NewAuth("secret"), time.Second, replyLen)
+	for i := 0; err != nil && i < 100; i++ {
+		time.Sleep(5 * time.Millisecond)
+		entry, err = Fetch(srv.Addr(), "example.ru", NewAuth("secret"), time.Second, replyLen)
+	}
+	if err != nil || entry.Raw != "domain: example.ru\r\n" {
+		t.Fatalf("signed fetch failed: %+v %v", entry, err)
+	}
+
+	for _, auth := range []Auth{{}, NewAuth("other")} {
+		if _, err := Fetch(srv.Addr(), "example.ru", auth, time.Second, replyLen); err == nil {
+			t.Errorf("no error for fetch with secret %q", auth.secret)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	auth := NewAuth("secret")
+	now := time.Now()
+
+	args, ts := auth.signRequest("example.ru", now)
+	if fqdn, got, err := auth.verifyRequest(args, now); err != nil || fqdn != "example.ru" || got != ts {
+		t.Errorf("signed request rejected: %q %q %v", fqdn, got, err)
+	}
+	if _, _, err := auth.verifyRequest(strings.Replace(args, "example.ru", "other.ru", 1), now); err != ErrUnauthorized {
+		t.Errorf("unexpected error for tampered request: %v", err)
+	}
+	if _, _, err := auth.verifyRequest(args, now.Add(MaxSkew*2)); err != ErrStale {
+		t.Errorf("unexpected error for replayed request: %v", err)
+	}
+
+	payload := []byte(`{"raw":"domain: example.ru"}`)
+	reply := auth.signReply("example.ru", ts, payload) + " " + string(payload)
+	if got, err := auth.verifyReply("example.ru", ts, reply); err != nil || got != string(payload) {
+		t.Errorf("signed reply rejected: %q %v", got, err)
+	}
+	if _, err := auth.verifyReply("example.ru", ts, strings.Replace(reply, "example.ru\"", "evil.ru\"", 1)); err != ErrUnauthorized {
+		t.Errorf("unexpected error for tampered reply: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package peer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const ReplicasDefault = 100
+
+// Ring - consistent hashing: у каждого ключа одна реплика-владелец, при изменении списка реплик
+// меняет владельца только небольшая доля ключей
+type Ring struct {
+	replicas int
+
+	mu     sync.RWMutex
+	hashes []uint32
+	owners map[uint32]string
+	nodes  []string
+}
+
+func NewRing(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		replicas = ReplicasDefault
+	}
+
+	r := &Ring{replicas: replicas}
+	r.Set(nodes...)
+
+	return r
+}
+
+// Set - заменить список реплик (адреса host:port)
+func (r *Ring) Set(nodes ...string) {
+	hashes := make([]uint32, 0, len(nodes)*r.replicas)
+	owners := make(map[uint32]string, len(nodes)*r.replicas)
+
+	uniq := map[string]bool{}
+	for _, node := range nodes {
+		if node == "" || uniq[node] {
+			continue
+		}
+		uniq[node] = true
+
+		for i := 0; i < r.replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + "#" + node))
+			if _, found := owners[h]; found { // коллизия - оставляем первого
+				continue
+			}
+			owners[h] = node
+			hashes = append(hashes, h)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	list := make([]string, 0, len(uniq))
+	for node := range uniq {
+		list = append(list, node)
+	}
+	sort.Strings(list)
+
+	r.mu.Lock()
+	r.hashes, r.owners, r.nodes = hashes, owners, list
+	r.mu.Unlock()
+}
+
+// Nodes - текущий список реплик
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string{}, r.nodes...)
+}
+
+// Owner - реплика-владелец ключа, "" - если реплик нет
+func (r *Ring) Owner(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]]
+}
@@ -0,0 +1,68 @@
+package peer
+
+import (
+	"fmt"
+	"testing"
+)
+
+// go test -covermode=count -coverprofile=coverage.cov && go tool cover -html=coverage.cov
+
+func TestRing_Owner(t *testing.T) {
+	nodes := []string{"10.0.0.1:4344", "10.0.0.2:4344", "10.0.0.3:4344"}
+	ring := NewRing(0, nodes...)
+
+	counts := map[string]int{}
+	owners := map[string]string{}
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("domain%d.ru", i)
+		owner := ring.Owner(key)
+		if owner != ring.Owner(key) {
+			t.Fatalf("owner of %s is not stable", key)
+		}
+		counts[owner]++
+		owners[key] = owner
+	}
+
+	for _, node := range nodes {
+		if counts[node] < 500 {
+			t.Errorf("bad keys distribution: %v", counts)
+		}
+	}
+
+	// после удаления реплики владелец меняется только у ее ключей
+	ring.Set(nodes[:2]...)
+	for key, owner := range owners {
+		if owner != nodes[2] && ring.Owner(key) != owner {
+			t.Fatalf("owner of %s changed without reason: %s -> %s", key, owner, ring.Owner(key))
+		}
+	}
+}
+
+func TestRing_Empty(t *testing.T) {
+	ring := NewRing(10)
+	if owner := ring.Owner("example.ru"); owner != "" {
+		t.Errorf("owner in empty ring: %s", owner)
+	}
+
+	ring.Set("10.0.0.1:4344", "10.0.0.1:4344", "")
+	if nodes := ring.Nodes(); len(nodes) != 1 {
+		t.Errorf("unexpected nodes: %v", nodes)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	nodes, err := Discover([]string{"10.0.0.2:4344", "10.0.0.1:4344", "10.0.0.2:4344"}, "localhost", "4344")
+	if err != nil {
+		t.Fatalf("discover error: %v", err)
+	}
+
+	found := false
+	for _, node := range nodes {
+		if node == "127.0.0.1:4344" || node == "[::1]:4344" {
+			found = true
+		}
+	}
+	if !found || len(nodes) < 3 {
+		t.Errorf("unexpected discovered nodes: %v", nodes)
+	}
+}
@@ -0,0 +1,158 @@
+package psl
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	privateBegin = "// ===BEGIN PRIVATE DOMAINS==="
+	privateEnd   = "// ===END PRIVATE DOMAINS==="
+)
+
+// registryZones - регистрационные зоны .ru/.su из секции PRIVATE: домены в них регистрируются
+// как в зонах верхнего уровня и обслуживаются whois серверами регистратуры
+var registryZones = map[string]bool{
+	"adygeya.ru": true, "bashkiria.ru": true, "bir.ru": true, "cbg.ru": true, "com.ru": true,
+	"dagestan.ru": true, "grozny.ru": true, "kalmykia.ru": true, "kustanai.ru": true, "marine.ru": true,
+	"mordovia.ru": true, "msk.ru": true, "mytis.ru": true, "nalchik.ru": true, "net.ru": true,
+	"nov.ru": true, "org.ru": true, "pp.ru": true, "pyatigorsk.ru": true, "spb.ru": true,
+	"vladikavkaz.ru": true, "vladimir.ru": true,
+
+	"abkhazia.su": true, "adygeya.su": true, "aktyubinsk.su": true, "arkhangelsk.su": true, "armenia.su": true,
+	"ashgabad.su": true, "azerbaijan.su": true, "balashov.su": true, "bashkiria.su": true, "bryansk.su": true,
+	"bukhara.su": true, "chimkent.su": true, "dagestan.su": true, "east-kazakhstan.su": true, "exnet.su": true,
+	"georgia.su": true, "grozny.su": true, "ivanovo.su": true, "jambyl.su": true, "kalmykia.su": true,
+	"kaluga.su": true, "karacol.su": true, "karaganda.su": true, "karelia.su": true, "khakassia.su": true,
+	"krasnodar.su": true, "kurgan.su": true, "kustanai.su": true, "lenug.su": true, "mangyshlak.su": true,
+	"mordovia.su": true, "msk.su": true, "murmansk.su": true, "nalchik.su": true, "navoi.su": true,
+	"north-kazakhstan.su": true, "nov.su": true, "obninsk.su": true, "penza.su": true, "pokrovsk.su": true,
+	"sochi.su": true, "spb.su": true, "tashkent.su": true, "termez.su": true, "togliatti.su": true,
+	"troitsk.su": true, "tselinograd.su": true, "tula.su": true, "tuva.su": true, "vladikavkaz.su": true,
+	"vladimir.su": true, "vologda.su": true,
+}
+
+// List - правила Public Suffix List (формат public_suffix_list.dat).
+// Учитываются секция ICANN и из секции PRIVATE только регистрационные зоны registryZones:
+// суффиксы платформ (github.io, blogspot.com) для whois не публичные, foo.github.io -> github.io.
+// nil - встроенный список golang.org/x/net/publicsuffix.
+type List struct {
+	rules      map[string]bool // "co.uk"
+	wildcards  map[string]bool // "*.ck" -> "ck"
+	exceptions map[string]bool // "!www.ck" -> "www.ck"
+}
+
+// Parse - список из public_suffix_list.dat, правила в Unicode переводятся в punycode
+func Parse(r io.Reader) (*List, error) {
+	l := &List{rules: map[string]bool{}, wildcards: map[string]bool{}, exceptions: map[string]bool{}}
+
+	private := false
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		switch line := strings.TrimSpace(scanner.Text()); {
+		case strings.HasPrefix(line, privateBegin):
+			private = true
+		case strings.HasPrefix(line, privateEnd):
+			private = false
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+
+		rule, target := fields[0], l.rules
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			rule, target = rule[1:], l.exceptions
+		case strings.HasPrefix(rule, "*."):
+			rule, target = rule[2:], l.wildcards
+		}
+
+		ascii, err := idna.Lookup.ToASCII(rule)
+		if err != nil || ascii == "" {
+			return nil, errors.Errorf("line %d: bad rule %q", n, fields[0])
+		}
+		if private && (rule != fields[0] || !registryZones[ascii]) { // исключения и wildcard платформ
+			continue
+		}
+		target[ascii] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithMessage(err, "can't read public suffix list")
+	}
+
+	if l.Rules() == 0 {
+		return nil, errors.New("public suffix list is empty")
+	}
+
+	return l, nil
+}
+
+// Rules - число правил списка
+func (l *List) Rules() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// PublicSuffix - публичный суффикс домена (punycode, lower case): example.co.uk -> co.uk.
+// Домен без подходящего правила - последняя метка (правило "*").
+func (l *List) PublicSuffix(domain string) string {
+	if l == nil {
+		return embeddedSuffix(domain)
+	}
+
+	labels := strings.Split(domain, ".")
+
+	// исключения важнее остальных правил
+	for i := 0; i < len(labels)-1; i++ {
+		if l.exceptions[strings.Join(labels[i:], ".")] {
+			return strings.Join(labels[i+1:], ".")
+		}
+	}
+
+	// самое длинное совпадение
+	for i := range labels {
+		if l.rules[strings.Join(labels[i:], ".")] {
+			return strings.Join(labels[i:], ".")
+		}
+		if i+1 < len(labels) && l.wildcards[strings.Join(labels[i+1:], ".")] {
+			return strings.Join(labels[i:], ".")
+		}
+	}
+
+	return labels[len(labels)-1]
+}
+
+// Registrable - регистрируемый домен (суффикс и одна метка перед ним):
+// shop.customer.example.co.uk -> example.co.uk. false - домен сам является публичным суффиксом.
+func (l *List) Registrable(domain string) (string, bool) {
+	suffix := l.PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return domain, false
+	}
+
+	rest := domain[:len(domain)-len(suffix)-1]
+	return rest[strings.LastIndex(rest, ".")+1:] + "." + suffix, true
+}
+
+// embeddedSuffix - публичный суффикс по встроенному списку без правил секции PRIVATE (кроме registryZones):
+// после совпадения с ними суффикс ищется заново без его первой метки
+func embeddedSuffix(domain string) string {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	for !icann && !registryZones[suffix] {
+		dot := strings.IndexByte(suffix, '.')
+		if dot < 0 {
+			return suffix // зона без правила
+		}
+		suffix, icann = publicsuffix.PublicSuffix(suffix[dot+1:])
+	}
+	return suffix
+}
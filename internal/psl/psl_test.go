@@ -0,0 +1,130 @@
+package psl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testList = `// ===BEGIN ICANN DOMAINS===
+uk
+co.uk
+ru
+su
+com.ru
+рф
+*.ck
+!www.ck
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+msk.ru
+spb.su
+github.io
+*.platform.com
+// ===END PRIVATE DOMAINS===
+`
+
+func TestList(t *testing.T) {
+	file, err := Parse(strings.NewReader(testList))
+	if err != nil {
+		t.Fatalf("list not parsed: %v", err)
+	}
+	if file.Rules() != 10 { // без github.io и *.platform.com
+		t.Errorf("unexpected rules count: %d", file.Rules())
+	}
+
+	testCases := []struct {
+		domain      string
+		suffix      string
+		registrable string
+	}{
+		{"shop.customer.example.co.uk", "co.uk", "example.co.uk"},
+		{"example.co.uk", "co.uk", "example.co.uk"},
+		{"co.uk", "co.uk", "co.uk"},
+		{"www.example.com.ru", "com.ru", "example.com.ru"},
+		{"example.msk.ru", "msk.ru", "example.msk.ru"},
+		{"sub.example.ru", "ru", "example.ru"},
+		{"ru", "ru", "ru"},
+		{"www.xn--80atjc.xn--p1ai", "xn--p1ai", "xn--80atjc.xn--p1ai"},
+		{"a.example.ck", "example.ck", "a.example.ck"},
+		{"a.www.ck", "ck", "www.ck"},
+		{"foo.github.io", "io", "github.io"},
+		{"myapp.herokuapp.com", "com", "herokuapp.com"},
+		{"x.blogspot.com", "com", "blogspot.com"},
+		{"a.b.platform.com", "com", "platform.com"},
+		{"shop.example.spb.su", "spb.su", "example.spb.su"},
+	}
+
+	for _, l := range []*List{file, nil} {
+		for n, test := range testCases {
+			if l == nil && strings.HasSuffix(test.domain, ".ck") {
+				continue // встроенный список может отличаться для .ck
+			}
+			if suffix := l.PublicSuffix(test.domain); suffix != test.suffix {
+				t.Errorf("unexpected suffix for test case #%d (embedded %v): %s <> %s", n, l == nil, suffix, test.suffix)
+			}
+			if registrable, _ := l.Registrable(test.domain); registrable != test.registrable {
+				t.Errorf("unexpected registrable for test case #%d (embedded %v): %s <> %s", n, l == nil, registrable, test.registrable)
+			}
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("// only comments\n")); err == nil {
+		t.Error("no error for empty list")
+	}
+}
+
+func TestSource_Reload(t *testing.T) {
+	embedded, err := NewSource("")
+	if err != nil || embedded.List() != nil {
+		t.Fatalf("unexpected embedded source: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "psl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "public_suffix_list.dat")
+	if _, err := NewSource(path); err == nil {
+		t.Error("no error for missing file")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("ru\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSource(path)
+	if err != nil {
+		t.Fatalf("source not created: %v", err)
+	}
+	if suffix := s.List().PublicSuffix("example.com.ru"); suffix != "ru" {
+		t.Errorf("unexpected suffix: %s", suffix)
+	}
+
+	if reloaded, err := s.Reload(); reloaded || err != nil {
+		t.Errorf("unexpected reload of unchanged file: %v %v", reloaded, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("ru\ncom.ru\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	if reloaded, err := s.Reload(); !reloaded || err != nil {
+		t.Errorf("changed file not reloaded: %v %v", reloaded, err)
+	}
+	if suffix := s.List().PublicSuffix("example.com.ru"); suffix != "com.ru" {
+		t.Errorf("unexpected suffix after reload: %s", suffix)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("ru\nbad_rule.ru\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second))
+	if _, err := s.Reload(); err == nil || s.List().Rules() != 2 {
+		t.Errorf("bad file replaced list: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package psl
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Source - текущий список: встроенный или из файла, при изменении файла подменяется атомарно целиком
+type Source struct {
+	path string
+
+	list atomic.Value // *List
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// NewSource - path "" - только встроенный список. Ошибка чтения файла при создании - ошибка.
+func NewSource(path string) (*Source, error) {
+	s := &Source{path: path}
+	s.list.Store((*List)(nil))
+
+	if path == "" {
+		return s, nil
+	}
+
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// List - текущий список (nil - встроенный)
+func (s *Source) List() *List {
+	if s == nil {
+		return nil
+	}
+	return s.list.Load().(*List)
+}
+
+// Reload - перечитать файл, если он изменился (время модификации или размер).
+// При ошибке остается предыдущий список.
+func (s *Source) Reload() (bool, error) {
+	if s == nil || s.path == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return false, errors.WithMessage(err, "can't stat public suffix list")
+	}
+	if fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return false, nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return false, errors.WithMessage(err, "can't open public suffix list")
+	}
+	defer f.Close()
+
+	l, err := Parse(f)
+	if err != nil {
+		return false, errors.WithMessagef(err, "can't parse %s", s.path)
+	}
+
+	s.list.Store(l)
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+
+	return true, nil
+}
@@ -0,0 +1,51 @@
+package resolve
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const CacheTTLDefault = 24 * time.Hour
+
+// cache - результаты внешних источников по зоне, чтобы не спрашивать их на каждый запрос
+type cache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	items map[string]cacheItem
+}
+
+type cacheItem struct {
+	target  Target
+	err     error
+	expires time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	if ttl <= 0 {
+		ttl = CacheTTLDefault
+	}
+	return &cache{ttl: ttl, items: map[string]cacheItem{}}
+}
+
+// resolve - результат из кэша или resolve(). Сохраняются найденный сервер и отказы источника (ErrNoTarget),
+// сбои (сеть и т.д.) - нет.
+func (c *cache) resolve(zone string, resolve func() (Target, error)) (Target, error) {
+	c.mu.Lock()
+	item, found := c.items[zone]
+	c.mu.Unlock()
+	if found && time.Now().Before(item.expires) {
+		return item.target, item.err
+	}
+
+	target, err := resolve()
+	if err == nil || errors.Cause(err) == ErrNoTarget {
+		c.mu.Lock()
+		c.items[zone] = cacheItem{target: target, err: err, expires: time.Now().Add(c.ttl)}
+		c.mu.Unlock()
+	}
+
+	return target, err
+}
@@ -0,0 +1,59 @@
+package resolve
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const DNSSuffixDefault = "whois-servers.net"
+
+// LookupCNAMEFunc - каноническое имя хоста (net.Resolver.LookupCNAME)
+type LookupCNAMEFunc func(ctx context.Context, host string) (string, error)
+
+// DNS - соглашение "<tld>.whois-servers.net": CNAME на whois сервер зоны
+type DNS struct {
+	suffix string
+	lookup LookupCNAMEFunc
+	cache  *cache
+}
+
+func NewDNS(suffix string, lookup LookupCNAMEFunc, ttl time.Duration) *DNS {
+	if suffix == "" {
+		suffix = DNSSuffixDefault
+	}
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupCNAME
+	}
+	return &DNS{suffix: strings.Trim(suffix, "."), lookup: lookup, cache: newCache(ttl)}
+}
+
+func (r *DNS) Name() string {
+	return "dns"
+}
+
+func (r *DNS) Resolve(ctx context.Context, fqdn string) (Target, error) {
+	tld := TLD(fqdn)
+	return r.cache.resolve(tld, func() (Target, error) {
+		name := tld + "." + r.suffix
+
+		cname, err := r.lookup(ctx, name)
+		if dnsErr, ok := err.(*net.DNSError); ok && !dnsErr.Timeout() && !dnsErr.Temporary() {
+			return Target{}, errors.WithMessagef(ErrNoTarget, "%s not found", name)
+		}
+		if err != nil {
+			return Target{}, errors.WithMessagef(err, "lookup %s", name)
+		}
+
+		host := strings.ToLower(strings.TrimSuffix(cname, "."))
+		reason := name + " CNAME " + host
+		if host == name { // без CNAME - сам хост whois сервер
+			reason = name + " has address"
+		}
+
+		return Target{Host: host, Port: whoisPort, Resolver: r.Name(), Reason: reason}, nil
+	})
+}
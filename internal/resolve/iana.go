@@ -0,0 +1,64 @@
+package resolve
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const IANAServerDefault = "whois.iana.org:43"
+
+// QueryFunc - запрос к whois серверу addr (host:port)
+type QueryFunc func(ctx context.Context, addr, query string) (string, error)
+
+// IANA - whois сервер зоны из ответа whois.iana.org на запрос TLD (поле "whois:")
+type IANA struct {
+	server string
+	query  QueryFunc
+	cache  *cache
+}
+
+func NewIANA(server string, query QueryFunc, ttl time.Duration) *IANA {
+	if server == "" {
+		server = IANAServerDefault
+	}
+	host, port := splitAddr(server)
+	return &IANA{server: net.JoinHostPort(host, port), query: query, cache: newCache(ttl)}
+}
+
+func (r *IANA) Name() string {
+	return "iana"
+}
+
+func (r *IANA) Resolve(ctx context.Context, fqdn string) (Target, error) {
+	tld := TLD(fqdn)
+	return r.cache.resolve(tld, func() (Target, error) {
+		raw, err := r.query(ctx, r.server, tld)
+		if err != nil {
+			return Target{}, errors.WithMessagef(err, "query %s for %s", r.server, tld)
+		}
+
+		host := ianaWhois(raw)
+		if host == "" {
+			return Target{}, errors.WithMessagef(ErrNoTarget, "%s has no whois server for zone %s", r.server, tld)
+		}
+
+		return Target{Host: host, Port: whoisPort, Resolver: r.Name(), Reason: "whois: of zone " + tld + " at " + r.server}, nil
+	})
+}
+
+// ianaWhois - значение поля "whois:" ответа IANA
+func ianaWhois(raw string) string {
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, ":"); i > 0 && strings.EqualFold(line[:i], "whois") {
+			return strings.ToLower(strings.TrimSpace(line[i+1:]))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RDAPBootstrapDefault = "https://data.iana.org/rdap/dns.json"
+	RDAPTimeoutDefault   = 10 * time.Second
+
+	rdapMaxBody = 1 << 20
+)
+
+// errRDAPNotFound - объекта нет на RDAP сервере (404), для домена - не зарегистрирован
+var errRDAPNotFound = errors.New("rdap object not found")
+
+// RDAP - RDAP сервер зоны из bootstrap реестра IANA (RFC 7484), whois сервер - поле port43 ответа
+// RDAP сервера на запрос домена (RFC 9083)
+type RDAP struct {
+	bootstrap string
+	client    *http.Client
+	ttl       time.Duration
+	cache     *cache
+
+	mu       sync.Mutex
+	services map[string]string // зона -> базовый URL RDAP сервера
+	loadedAt time.Time
+}
+
+func NewRDAP(bootstrap string, timeout, ttl time.Duration) *RDAP {
+	if bootstrap == "" {
+		bootstrap = RDAPBootstrapDefault
+	}
+	if timeout <= 0 {
+		timeout = RDAPTimeoutDefault
+	}
+	if ttl <= 0 {
+		ttl = CacheTTLDefault
+	}
+
+	return &RDAP{bootstrap: bootstrap, client: &http.Client{Timeout: timeout}, ttl: ttl, cache: newCache(ttl)}
+}
+
+func (r *RDAP) Name() string {
+	return "rdap"
+}
+
+// Resolve - port43 из ответа RDAP сервера на запрос домена. port43 - whois сервер реестра, общий для зоны,
+// поэтому найденный сервер кэшируется по зоне. Незарегистрированный домен (404) - ErrNoTarget только для него,
+// в кэш зоны не попадает.
+func (r *RDAP) Resolve(ctx context.Context, fqdn string) (Target, error) {
+	tld := TLD(fqdn)
+	unregistered := false
+	target, err := r.cache.resolve(tld, func() (Target, error) {
+		base, err := r.service(ctx, tld)
+		if err != nil {
+			return Target{}, err
+		}
+
+		url := strings.TrimSuffix(base, "/") + "/domain/" + fqdn
+		var domain struct {
+			Port43 string `json:"port43"`
+		}
+		if err := r.get(ctx, url, &domain); err != nil {
+			unregistered = errors.Cause(err) == errRDAPNotFound
+			return Target{}, err
+		}
+
+		host := strings.ToLower(strings.TrimSpace(domain.Port43))
+		if host == "" {
+			return Target{}, errors.WithMessagef(ErrNoTarget, "%s has no port43", url)
+		}
+
+		return Target{Host: host, Port: whoisPort, Resolver: r.Name(), Reason: "port43 of " + url}, nil
+	})
+
+	if unregistered {
+		return Target{}, errors.WithMessage(ErrNoTarget, err.Error())
+	}
+	return target, err
+}
+
+// service - базовый URL RDAP сервера зоны, bootstrap перечитывается раз в ttl (без блокировки остальных запросов)
+func (r *RDAP) service(ctx context.Context, tld string) (string, error) {
+	r.mu.Lock()
+	services, loadedAt := r.services, r.loadedAt
+	r.mu.Unlock()
+
+	if services == nil || time.Since(loadedAt) > r.ttl {
+		var err error
+		if services, err = r.loadBootstrap(ctx); err != nil {
+			return "", err
+		}
+
+		r.mu.Lock()
+		r.services, r.loadedAt = services, time.Now()
+		r.mu.Unlock()
+	}
+
+	base, found := services[tld]
+	if !found {
+		return "", errors.WithMessagef(ErrNoTarget, "no rdap service for zone %s in %s", tld, r.bootstrap)
+	}
+	return base, nil
+}
+
+// loadBootstrap - зона -> базовый URL RDAP сервера из bootstrap реестра
+func (r *RDAP) loadBootstrap(ctx context.Context) (map[string]string, error) {
+	var bootstrap struct {
+		Services [][][]string `json:"services"` // [[зоны], [URL]]
+	}
+	if err := r.get(ctx, r.bootstrap, &bootstrap); err != nil {
+		return nil, errors.WithMessage(err, "rdap bootstrap")
+	}
+
+	services := map[string]string{}
+	for _, service := range bootstrap.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+		for _, zone := range service[0] {
+			services[strings.ToLower(zone)] = preferHTTPS(service[1])
+		}
+	}
+	return services, nil
+}
+
+func (r *RDAP) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, rdapMaxBody)
+	if resp.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(ioutil.Discard, body)
+		return errors.WithMessagef(errRDAPNotFound, "%s", url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, body)
+		return errors.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+
+	return errors.WithMessagef(json.NewDecoder(body).Decode(v), "%s: bad json", url)
+}
+
+func preferHTTPS(urls []string) string {
+	for _, u := range urls {
+		if strings.HasPrefix(u, "https://") {
+			return u
+		}
+	}
+	return urls[0]
+}
@@ -0,0 +1,127 @@
+package resolve
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoTarget - резолвер не знает whois сервера для домена (причина - в тексте ошибки)
+var ErrNoTarget = errors.New("no whois server")
+
+const whoisPort = "43"
+
+type (
+	// Resolver - выбор whois сервера для домена (регистрируемый домен в punycode).
+	// Target.Reason - почему выбран сервер, ошибка - почему не выбран (ErrNoTarget или сбой источника).
+	Resolver interface {
+		Name() string
+		Resolve(ctx context.Context, fqdn string) (Target, error)
+	}
+
+	// Target - whois сервер домена
+	Target struct {
+		Host     string `json:"host"`
+		Port     string `json:"port"`
+		Resolver string `json:"resolver"`
+		Reason   string `json:"reason"`
+	}
+
+	// Step - шаг цепочки: выбранный сервер или причина отказа
+	Step struct {
+		Resolver string  `json:"resolver"`
+		Target   *Target `json:"target,omitempty"`
+		Error    string  `json:"error,omitempty"`
+	}
+
+	// Trace - как цепочка выбирала whois сервер, для отладки. Target - nil, если сервер не найден.
+	Trace struct {
+		Domain string  `json:"domain"`
+		Steps  []Step  `json:"steps"`
+		Target *Target `json:"target,omitempty"`
+	}
+
+	// Chain - резолверы по порядку, первый вернувший сервер определяет результат, следующие не опрашиваются
+	Chain struct {
+		resolvers []Resolver
+	}
+)
+
+func (t Target) Addr() string {
+	return net.JoinHostPort(t.Host, t.Port)
+}
+
+func NewChain(resolvers ...Resolver) *Chain {
+	return &Chain{resolvers: resolvers}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.resolvers))
+	for _, r := range c.resolvers {
+		names = append(names, r.Name())
+	}
+	return "chain(" + strings.Join(names, ", ") + ")"
+}
+
+func (c *Chain) Resolve(ctx context.Context, fqdn string) (Target, error) {
+	trace := c.Trace(ctx, fqdn)
+	if trace.Target == nil {
+		return Target{}, errors.WithMessagef(ErrNoTarget, "%s: nothing found for %s", c.Name(), fqdn)
+	}
+	return *trace.Target, nil
+}
+
+// Trace - Resolve с записью каждого шага
+func (c *Chain) Trace(ctx context.Context, fqdn string) Trace {
+	trace := Trace{Domain: fqdn, Steps: make([]Step, 0, len(c.resolvers))}
+
+	for _, r := range c.resolvers {
+		target, err := r.Resolve(ctx, fqdn)
+		step := Step{Resolver: r.Name()}
+		if err != nil {
+			step.Error = err.Error()
+		} else {
+			step.Target = &target
+		}
+		trace.Steps = append(trace.Steps, step)
+
+		if step.Target != nil {
+			trace.Target = step.Target
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return trace
+}
+
+// Zones - зоны домена от ближайшей: sub.example.co.uk -> [example.co.uk co.uk uk]
+func Zones(fqdn string) []string {
+	var zones []string
+	for zone := fqdn; zone != ""; {
+		zones = append(zones, zone)
+		i := strings.Index(zone, ".")
+		if i < 0 {
+			break
+		}
+		zone = zone[i+1:]
+	}
+	return zones
+}
+
+// TLD - последняя метка домена
+func TLD(fqdn string) string {
+	return fqdn[strings.LastIndex(fqdn, ".")+1:]
+}
+
+// splitAddr - "host" или "host:port" (порт по умолчанию 43)
+func splitAddr(addr string) (string, string) {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		return host, port
+	}
+	return addr, whoisPort
+}
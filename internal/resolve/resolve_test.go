@@ -0,0 +1,135 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestChain(t *testing.T) {
+	chain := NewChain(
+		Static{"co.uk": "whois.nic.uk", "ru": "whois.tcinet.ru:43"},
+		NewDNS("", func(_ context.Context, host string) (string, error) {
+			if host != "com.whois-servers.net" {
+				return "", &net.DNSError{Err: "no such host", Name: host}
+			}
+			return "whois.verisign-grs.com.", nil
+		}, 0),
+		Default("whois.myorderbox.com:4343"),
+	)
+
+	testCases := []struct {
+		domain   string
+		addr     string
+		resolver string
+		steps    int
+	}{
+		{"example.ru", "whois.tcinet.ru:43", "static", 1},
+		{"example.co.uk", "whois.nic.uk:43", "static", 1},
+		{"example.com", "whois.verisign-grs.com:43", "dns", 2},
+		{"example.online", "whois.myorderbox.com:4343", "default", 3},
+	}
+
+	for _, test := range testCases {
+		trace := chain.Trace(context.Background(), test.domain)
+		if trace.Target == nil || trace.Target.Addr() != test.addr || trace.Target.Resolver != test.resolver {
+			t.Errorf("%s: unexpected target %+v", test.domain, trace.Target)
+		}
+		if len(trace.Steps) != test.steps {
+			t.Errorf("%s: unexpected steps %+v", test.domain, trace.Steps)
+		}
+		for _, step := range trace.Steps[:len(trace.Steps)-1] {
+			if step.Error == "" {
+				t.Errorf("%s: no reason for skipped step %s", test.domain, step.Resolver)
+			}
+		}
+	}
+
+	if _, err := NewChain(Static{}).Resolve(context.Background(), "example.ru"); errors.Cause(err) != ErrNoTarget {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestIANA(t *testing.T) {
+	queries := 0
+	iana := NewIANA("", func(_ context.Context, addr, query string) (string, error) {
+		queries++
+		if addr != IANAServerDefault {
+			return "", fmt.Errorf("unexpected server %s", addr)
+		}
+		if query == "ru" {
+			return "% IANA WHOIS server\ndomain:       RU\nwhois:        whois.tcinet.ru\n", nil
+		}
+		return "% IANA WHOIS server\ndomain:       " + strings.ToUpper(query) + "\n", nil
+	}, 0)
+
+	for i := 0; i < 2; i++ {
+		target, err := iana.Resolve(context.Background(), "example.ru")
+		if err != nil || target.Addr() != "whois.tcinet.ru:43" {
+			t.Fatalf("unexpected result: %+v, %v", target, err)
+		}
+	}
+	if _, err := iana.Resolve(context.Background(), "example.test"); errors.Cause(err) != ErrNoTarget {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if queries != 2 {
+		t.Errorf("answers are not cached: %d queries", queries)
+	}
+}
+
+func TestRDAP(t *testing.T) {
+	var (
+		srv     *httptest.Server
+		domains int
+	)
+	srv = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dns.json":
+			fmt.Fprintf(rw, `{"services": [[["net", "com"], ["%s/rdap/"]]]}`, srv.URL)
+		case r.URL.Path == "/rdap/domain/free-example.com":
+			domains++
+			http.NotFound(rw, r)
+		case strings.HasPrefix(r.URL.Path, "/rdap/domain/"):
+			domains++
+			fmt.Fprint(rw, `{"ldhName": "EXAMPLE.COM", "port43": "whois.verisign-grs.com"}`)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer srv.Close()
+
+	rdap := NewRDAP(srv.URL+"/dns.json", 0, 0)
+
+	// незарегистрированный домен - ErrNoTarget только для него, отказ не кэшируется по зоне
+	if _, err := rdap.Resolve(context.Background(), "free-example.com"); errors.Cause(err) != ErrNoTarget {
+		t.Errorf("unexpected error for unregistered domain: %v", err)
+	}
+
+	target, err := rdap.Resolve(context.Background(), "example.com")
+	if err != nil || target.Addr() != "whois.verisign-grs.com:43" {
+		t.Fatalf("unexpected result: %+v, %v", target, err)
+	}
+	if !strings.Contains(target.Reason, "/rdap/domain/example.com") {
+		t.Errorf("unexpected reason: %s", target.Reason)
+	}
+
+	// whois сервер реестра общий для зоны: other.com из кэша зоны, other.net - новый запрос
+	for _, domain := range []string{"other.com", "other.net"} {
+		if target, err := rdap.Resolve(context.Background(), domain); err != nil || target.Host == "" {
+			t.Errorf("unexpected result for %s: %+v, %v", domain, target, err)
+		}
+	}
+	if domains != 3 {
+		t.Errorf("unexpected rdap domain queries: %d", domains)
+	}
+
+	if _, err := rdap.Resolve(context.Background(), "example.ru"); errors.Cause(err) != ErrNoTarget {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
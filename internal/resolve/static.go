@@ -0,0 +1,42 @@
+package resolve
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Static - whois серверы зон из конфигурации (domainZoneWhois): самая длинная зона домена, кроме самого домена
+type Static map[string]string
+
+func (s Static) Name() string {
+	return "static"
+}
+
+func (s Static) Resolve(_ context.Context, fqdn string) (Target, error) {
+	zones := Zones(fqdn)
+	for _, zone := range zones[1:] {
+		if addr, found := s[zone]; found {
+			host, port := splitAddr(addr)
+			return Target{Host: host, Port: port, Resolver: s.Name(), Reason: "zone " + zone + " in domainZoneWhois"}, nil
+		}
+	}
+
+	return Target{}, errors.WithMessagef(ErrNoTarget, "no zone of %s in domainZoneWhois", fqdn)
+}
+
+// Default - whois сервер по умолчанию (defaultWhois), последнее звено цепочки
+type Default string
+
+func (d Default) Name() string {
+	return "default"
+}
+
+func (d Default) Resolve(context.Context, string) (Target, error) {
+	if d == "" {
+		return Target{}, errors.WithMessage(ErrNoTarget, "defaultWhois is not set")
+	}
+
+	host, port := splitAddr(string(d))
+	return Target{Host: host, Port: port, Resolver: d.Name(), Reason: "defaultWhois"}, nil
+}
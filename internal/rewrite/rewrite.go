@@ -0,0 +1,358 @@
+package rewrite
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/parser"
+)
+
+const (
+	InsertBefore = "insertBefore" // Lines перед каждой строкой, совпавшей с Anchor
+	InsertAfter  = "insertAfter"  // Lines после каждой строки, совпавшей с Anchor
+	Replace      = "replace"      // замена Anchor на Replacement ($1 - группы) в каждой строке
+	DeleteLine   = "deleteLine"   // удаление строк, совпавших с Anchor
+	AppendFooter = "appendFooter" // Lines в конец ответа
+)
+
+// поля разобранного ответа, доступные для Match.Fields
+const (
+	FieldDomain     = "domain"
+	FieldRegistrar  = "registrar"
+	FieldNameserver = "nameserver"
+	FieldStatus     = "status"
+)
+
+type (
+	// Match - все заданные условия должны совпасть, внутри списка - любое из значений
+	Match struct {
+		Domains   []string          // "example.ru" - точно, "*.example.ru" - шаблон, ".example.ru" - домен и поддомены
+		Zones     []string          // доменные зоны
+		Upstreams []string          // whois сервер: host или host:port
+		Fields    map[string]string // поле ответа == значение (без учета регистра): registrar: BEGET-RU
+	}
+
+	Action struct {
+		Type        string
+		Anchor      string // regexp
+		Lines       []string
+		Replacement string
+	}
+
+	Rule struct {
+		Name    string
+		Match   Match
+		Actions []Action
+	}
+
+	// Input - параметры ответа для выбора правил
+	Input struct {
+		Domain   string
+		Upstream string
+		Raw      string // ответ whois сервера для Match.Fields, "" - текущий текст
+	}
+
+	// Engine - правила применяются по порядку, каждое к результату предыдущего
+	Engine struct {
+		rules []rule
+	}
+
+	rule struct {
+		name    string
+		match   Match
+		actions []action
+	}
+
+	action struct {
+		typ         string
+		anchor      *regexp.Regexp
+		lines       []string
+		replacement string
+	}
+)
+
+func New(rules []Rule) (*Engine, error) {
+	e := &Engine{}
+
+	for n, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(n+1)
+		}
+
+		compiled := rule{name: name, match: normalizeMatch(r.Match)}
+		for field := range compiled.match.Fields {
+			switch field {
+			case FieldDomain, FieldRegistrar, FieldNameserver, FieldStatus:
+			default:
+				return nil, errors.Errorf("rewrite rule %s: unknown field %q", name, field)
+			}
+		}
+
+		for _, a := range r.Actions {
+			ca, err := compileAction(a)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "rewrite rule %s", name)
+			}
+			compiled.actions = append(compiled.actions, ca)
+		}
+
+		e.rules = append(e.rules, compiled)
+	}
+
+	return e, nil
+}
+
+func compileAction(a Action) (action, error) {
+	ca := action{typ: a.Type, lines: a.Lines, replacement: a.Replacement}
+
+	switch a.Type {
+	case InsertBefore, InsertAfter, Replace, DeleteLine:
+		if a.Anchor == "" {
+			return action{}, errors.Errorf("action %s: anchor is required", a.Type)
+		}
+		re, err := regexp.Compile(a.Anchor)
+		if err != nil {
+			return action{}, errors.WithMessagef(err, "action %s: bad anchor %q", a.Type, a.Anchor)
+		}
+		ca.anchor = re
+	case AppendFooter:
+	default:
+		return action{}, errors.Errorf("unknown action %q", a.Type)
+	}
+
+	return ca, nil
+}
+
+// Inserter - вставка строк, известных только при обработке ответа (внешний источник), до или после
+// строк-якорей. Якорь компилируется один раз.
+type Inserter struct {
+	a action
+}
+
+func NewInserter(typ, anchor string) (*Inserter, error) {
+	if typ != InsertBefore && typ != InsertAfter {
+		return nil, errors.Errorf("inserter: unsupported action %q", typ)
+	}
+
+	a, err := compileAction(Action{Type: typ, Anchor: anchor})
+	if err != nil {
+		return nil, err
+	}
+	return &Inserter{a: a}, nil
+}
+
+// Apply - вставить lines у каждой строки-якоря
+func (i *Inserter) Apply(whoisInfo string, lines []string) string {
+	if len(lines) == 0 {
+		return whoisInfo
+	}
+
+	a := i.a
+	a.lines = lines
+	return a.apply(whoisInfo)
+}
+
+func normalizeMatch(m Match) Match {
+	lower := func(list []string) []string {
+		r := make([]string, 0, len(list))
+		for _, s := range list {
+			r = append(r, strings.ToLower(strings.TrimSpace(s)))
+		}
+		return r
+	}
+
+	n := Match{
+		Domains:   lower(m.Domains),
+		Zones:     lower(m.Zones),
+		Upstreams: lower(m.Upstreams),
+	}
+	for i, zone := range n.Zones {
+		n.Zones[i] = strings.Trim(zone, ".")
+	}
+	if len(m.Fields) > 0 {
+		n.Fields = map[string]string{}
+		for field, value := range m.Fields {
+			n.Fields[strings.ToLower(field)] = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+
+	return n
+}
+
+// Apply - применить подходящие правила к тексту ответа
+func (e *Engine) Apply(in Input, whoisInfo string) string {
+	if e == nil || len(e.rules) == 0 {
+		return whoisInfo
+	}
+
+	in.Domain = strings.ToLower(in.Domain)
+	in.Upstream = strings.ToLower(in.Upstream)
+	if in.Raw == "" {
+		in.Raw = whoisInfo
+	}
+
+	var record *parser.Record // разбирается только при наличии правил по полям
+	for _, r := range e.rules {
+		if len(r.match.Fields) > 0 && record == nil {
+			parsed := parser.Parse(in.Raw)
+			record = &parsed
+		}
+		if !r.match.matches(in, record) {
+			continue
+		}
+
+		for _, a := range r.actions {
+			whoisInfo = a.apply(whoisInfo)
+		}
+	}
+
+	return whoisInfo
+}
+
+// MayApply - есть правила, подходящие домену и whois серверу без учета условий по полям ответа
+func (e *Engine) MayApply(domain, upstream string) bool {
+	if e == nil {
+		return false
+	}
+
+	in := Input{Domain: strings.ToLower(domain), Upstream: strings.ToLower(upstream)}
+	for _, r := range e.rules {
+		m := r.match
+		m.Fields = nil
+		if m.matches(in, nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m Match) matches(in Input, record *parser.Record) bool {
+	if len(m.Domains) > 0 && !anyOf(m.Domains, func(d string) bool { return matchDomain(d, in.Domain) }) {
+		return false
+	}
+
+	if len(m.Zones) > 0 && !anyOf(m.Zones, func(z string) bool {
+		return in.Domain == z || strings.HasSuffix(in.Domain, "."+z)
+	}) {
+		return false
+	}
+
+	if len(m.Upstreams) > 0 && !anyOf(m.Upstreams, func(u string) bool {
+		return in.Upstream == u || strings.HasPrefix(in.Upstream, u+":")
+	}) {
+		return false
+	}
+
+	for field, value := range m.Fields {
+		if !matchField(record, field, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchDomain(pattern, domain string) bool {
+	switch {
+	case strings.HasPrefix(pattern, "."):
+		return domain == pattern[1:] || strings.HasSuffix(domain, pattern)
+	case strings.Contains(pattern, "*"):
+		matched, _ := path.Match(pattern, domain)
+		return matched
+	default:
+		return domain == pattern
+	}
+}
+
+func matchField(record *parser.Record, field, value string) bool {
+	switch field {
+	case FieldDomain:
+		return strings.ToLower(record.Domain) == value
+	case FieldRegistrar:
+		return strings.ToLower(record.Registrar) == value
+	case FieldNameserver:
+		return anyOf(record.Nameservers, func(ns string) bool { return ns == value })
+	case FieldStatus:
+		return anyOf(record.Status, func(s string) bool { return s == value })
+	}
+	return false
+}
+
+func anyOf(list []string, match func(string) bool) bool {
+	for _, s := range list {
+		if match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply - построчная обработка, вставленные строки получают окончание (\r\n или \n) строки-якоря
+func (a action) apply(whoisInfo string) string {
+	if a.typ == AppendFooter {
+		return appendFooter(whoisInfo, a.lines)
+	}
+
+	lines := strings.Split(whoisInfo, "\n")
+
+	result := make([]string, 0, len(lines)+len(a.lines))
+	for i, line := range lines {
+		last := i == len(lines)-1 // после завершающего \n - пустой "хвост"
+		text := strings.TrimSuffix(line, "\r")
+		cr := strings.TrimPrefix(line, text)
+
+		if last && line == "" {
+			result = append(result, line)
+			continue
+		}
+
+		matched := a.anchor.MatchString(text)
+		switch {
+		case !matched:
+			result = append(result, line)
+		case a.typ == InsertBefore:
+			result = append(result, withEnding(a.lines, cr)...)
+			result = append(result, line)
+		case a.typ == InsertAfter:
+			result = append(result, line)
+			result = append(result, withEnding(a.lines, cr)...)
+		case a.typ == Replace:
+			result = append(result, a.anchor.ReplaceAllString(text, a.replacement)+cr)
+		case a.typ == DeleteLine:
+		}
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// appendFooter - строки в конец ответа с окончанием строк ответа
+func appendFooter(whoisInfo string, footer []string) string {
+	if len(footer) == 0 {
+		return whoisInfo
+	}
+
+	eol := "\n"
+	if strings.Contains(whoisInfo, "\r\n") {
+		eol = "\r\n"
+	}
+
+	if whoisInfo != "" && !strings.HasSuffix(whoisInfo, "\n") {
+		whoisInfo += eol
+	}
+
+	return whoisInfo + strings.Join(footer, eol) + eol
+}
+
+func withEnding(lines []string, cr string) []string {
+	r := make([]string, 0, len(lines))
+	for _, l := range lines {
+		r = append(r, l+cr)
+	}
+	return r
+}
@@ -0,0 +1,184 @@
+package rewrite
+
+import (
+	"testing"
+)
+
+const (
+	ruAnswer = "domain:        EXAMPLE.RU\n" +
+		"nserver:       ns1.beget.com.\n" +
+		"registrar:     BEGET-RU\n" +
+		"source:        TCI\n"
+
+	comAnswer = "   Domain Name: EXAMPLE.COM\r\n" +
+		"   Registrar: Example Registrar, Inc.\r\n" +
+		">>> Last update of whois database: 2026-10-19T10:00:00Z <<<\r\n" +
+		"\r\n" +
+		"NOTICE: The expiration date displayed in this record is the date the\r\n"
+)
+
+func TestEngine_Apply(t *testing.T) {
+	engine, err := New([]Rule{
+		{
+			Name:  "beget customers",
+			Match: Match{Zones: []string{"ru"}, Fields: map[string]string{"registrar": "beget-ru"}},
+			Actions: []Action{
+				{Type: InsertAfter, Anchor: `^source:`, Lines: []string{"descr:         Hosted by Beget"}},
+				{Type: Replace, Anchor: `^(nserver:\s+)ns1\.`, Replacement: "${1}dns1."},
+			},
+		},
+		{
+			Name:  "verisign notice",
+			Match: Match{Upstreams: []string{"whois.verisign-grs.com"}},
+			Actions: []Action{
+				{Type: DeleteLine, Anchor: `^NOTICE:`},
+				{Type: InsertBefore, Anchor: `^>>> Last update`, Lines: []string{"   Reseller: Beget"}},
+				{Type: AppendFooter, Lines: []string{"% proxied by whois-proxy"}},
+			},
+		},
+		{
+			Name:    "subdomains only",
+			Match:   Match{Domains: []string{"*.example.ru"}},
+			Actions: []Action{{Type: AppendFooter, Lines: []string{"% subdomain"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("engine not created: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		in       Input
+		raw      string
+		expected string
+	}{
+		{
+			name: "ru by registrar",
+			in:   Input{Domain: "example.ru", Upstream: "whois.tcinet.ru:43"},
+			raw:  ruAnswer,
+			expected: "domain:        EXAMPLE.RU\n" +
+				"nserver:       dns1.beget.com.\n" +
+				"registrar:     BEGET-RU\n" +
+				"source:        TCI\n" +
+				"descr:         Hosted by Beget\n",
+		},
+		{
+			name: "verisign",
+			in:   Input{Domain: "example.com", Upstream: "whois.verisign-grs.com:43"},
+			raw:  comAnswer,
+			expected: "   Domain Name: EXAMPLE.COM\r\n" +
+				"   Registrar: Example Registrar, Inc.\r\n" +
+				"   Reseller: Beget\r\n" +
+				">>> Last update of whois database: 2026-10-19T10:00:00Z <<<\r\n" +
+				"\r\n" +
+				"% proxied by whois-proxy\r\n",
+		},
+		{
+			name:     "not matched",
+			in:       Input{Domain: "example.com", Upstream: "whois.tcinet.ru:43"},
+			raw:      ruAnswer,
+			expected: ruAnswer,
+		},
+		{
+			name:     "wildcard",
+			in:       Input{Domain: "www.example.ru", Upstream: "whois.tcinet.ru:43"},
+			raw:      "domain: WWW.EXAMPLE.RU",
+			expected: "domain: WWW.EXAMPLE.RU\n% subdomain\n",
+		},
+	}
+
+	for _, test := range testCases {
+		if got := engine.Apply(test.in, test.raw); got != test.expected {
+			t.Errorf("unexpected result for %s:\n%q", test.name, got)
+		}
+	}
+
+	var disabled *Engine
+	if got := disabled.Apply(Input{Domain: "example.ru"}, ruAnswer); got != ruAnswer {
+		t.Errorf("nil engine changed answer")
+	}
+}
+
+func TestEngine_MayApply(t *testing.T) {
+	e, err := New([]Rule{
+		{Match: Match{Zones: []string{"ru"}, Fields: map[string]string{FieldRegistrar: "BEGET-RU"}}},
+		{Match: Match{Upstreams: []string{"whois.verisign-grs.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("engine not created: %v", err)
+	}
+
+	testCases := []struct {
+		domain   string
+		upstream string
+		expected bool
+	}{
+		{"example.ru", "whois.tcinet.ru:43", true}, // условие по полям неизвестно до ответа
+		{"example.com", "whois.verisign-grs.com:43", true},
+		{"example.org", "whois.pir.org:43", false},
+	}
+
+	for n, test := range testCases {
+		if res := e.MayApply(test.domain, test.upstream); res != test.expected {
+			t.Errorf("unexpected result for test case #%d: %v", n, res)
+		}
+	}
+
+	if (*Engine)(nil).MayApply("example.ru", "") {
+		t.Error("nil engine may apply")
+	}
+}
+
+func TestMatchDomain(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		domain  string
+		matched bool
+	}{
+		{"example.ru", "example.ru", true},
+		{"example.ru", "www.example.ru", false},
+		{"*.example.ru", "www.example.ru", true},
+		{"*.example.ru", "example.ru", false},
+		{".example.ru", "example.ru", true},
+		{".example.ru", "a.b.example.ru", true},
+		{".example.ru", "badexample.ru", false},
+	}
+
+	for n, test := range testCases {
+		if matchDomain(test.pattern, test.domain) != test.matched {
+			t.Errorf("unexpected result for test case #%d: %s %s", n, test.pattern, test.domain)
+		}
+	}
+}
+
+func TestNew_Negative(t *testing.T) {
+	for _, rules := range [][]Rule{
+		{{Actions: []Action{{Type: "unknown"}}}},
+		{{Actions: []Action{{Type: InsertAfter}}}},
+		{{Actions: []Action{{Type: DeleteLine, Anchor: "("}}}},
+		{{Match: Match{Fields: map[string]string{"owner": "x"}}}},
+	} {
+		if _, err := New(rules); err == nil {
+			t.Errorf("no error for rules: %+v", rules)
+		}
+	}
+}
+
+func TestInserter(t *testing.T) {
+	i, err := NewInserter(InsertAfter, `^source:`)
+	if err != nil {
+		t.Fatalf("inserter not created: %v", err)
+	}
+
+	const raw = "domain:        EXAMPLE.RU\r\nsource:        TCI\r\n"
+	if got := i.Apply(raw, []string{"descr:         one", "descr:         two"}); got != raw+"descr:         one\r\ndescr:         two\r\n" {
+		t.Errorf("unexpected result: %q", got)
+	}
+	if got := i.Apply(raw, nil); got != raw {
+		t.Errorf("unexpected result without lines: %q", got)
+	}
+
+	if _, err := NewInserter(Replace, `^source:`); err == nil {
+		t.Error("no error for unsupported inserter action")
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"net"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -24,6 +25,8 @@ type Server struct {
 	port          string
 	l             net.Listener
 	handler       HandlerFunc
+	closed        chan struct{}
+	closeOnce     sync.Once
 }
 
 func New(connType ConnType, host, port string, maxCntConnect int) (*Server, error) {
@@ -39,6 +42,7 @@ func New(connType ConnType, host, port string, maxCntConnect int) (*Server, erro
 		port:          port,
 		l:             nil,
 		handler:       nil,
+		closed:        make(chan struct{}),
 	}, nil
 }
 
@@ -64,8 +68,25 @@ func (s *Server) Listen() (err error) {
 	return err
 }
 
+// Close - остановить прием новых соединений
+func (s *Server) Close() (err error) {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		if s.l != nil {
+			err = s.l.Close()
+		}
+	})
+	return err
+}
+
 func (s *Server) Start(chErr chan<- error) {
 	defer func() {
+		select {
+		case <-s.closed: // уже закрыт в Close()
+			return
+		default:
+		}
+
 		if s.l != nil {
 			err := s.l.Close()
 			if err != nil {
@@ -84,6 +105,11 @@ func (s *Server) Start(chErr chan<- error) {
 		// Listen for an incoming connection.
 		conn, err := s.l.Accept()
 		if err != nil {
+			select {
+			case <-s.closed: // listener закрыт через Close()
+				return
+			default:
+			}
 			chErr <- errors.WithMessage(err, "problem accept new connection. net.Listener Accept()")
 			continue
 		}
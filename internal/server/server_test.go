@@ -87,3 +87,27 @@ func TestServer_ListenAndServe_Negative(t *testing.T) {
 		t.Errorf("no error for nil error chan")
 	}
 }
+
+func TestServer_Close(t *testing.T) {
+	server, _ := New("tcp", "localhost", "50002", 1)
+
+	nothingFunc := func(conn net.Conn) error {
+		return conn.Close()
+	}
+	chErr := make(chan error, 1)
+
+	if err := server.ListenAndServe(nothingFunc, chErr); err != nil {
+		t.Fatalf("can't do server.ListenAndServe(): %v", err)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Errorf("can't close server: %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Errorf("second close returns error: %v", err)
+	}
+
+	if _, err := net.Dial("tcp", server.Addr()); err == nil {
+		t.Errorf("server accepts connections after Close()")
+	}
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Cache - общий интерфейс хранилищ whois ответов (memory, redis, disk)
+	Cache interface {
+		GetEntry(fqdn FQDN) (Entry, bool, error)
+		SetEntry(fqdn FQDN, entry Entry) error
+		Remove(fqdn FQDN) error
+		RemoveAll() error
+		Stats() Stats
+		Close() error
+	}
+
+	// Stats - текущее состояние и счетчики хранилища
+	Stats struct {
+		Entries   int   // -1 - неизвестно (внешнее хранилище)
+		Bytes     int64 // -1 - неизвестно (внешнее хранилище)
+		Hits      uint64
+		Misses    uint64
+		Expired   uint64 // removed by TTL
+		Evictions uint64 // removed by size limits (LRU)
+	}
+
+	// counters - счетчики hit/miss/expired/eviction, общие для всех хранилищ
+	counters struct {
+		hits      uint64
+		misses    uint64
+		expired   uint64
+		evictions uint64
+	}
+
+	// Entry - whois ответ с метаданными
+	Entry struct {
+		Raw       string        `json:"raw"`
+		FetchedAt time.Time     `json:"fetchedAt"`
+		TTL       time.Duration `json:"ttl"` // 0 - TTL хранилища
+		Upstream  string        `json:"upstream,omitempty"`
+		Class     string        `json:"class,omitempty"`
+	}
+)
+
+// ExpiresAt - момент устаревания записи
+func (e Entry) ExpiresAt() time.Time {
+	return e.FetchedAt.Add(e.TTL)
+}
+
+// Age - возраст записи (время с момента получения от whois сервера)
+func (e Entry) Age() time.Duration {
+	return time.Since(e.FetchedAt)
+}
+
+// Remaining - оставшееся время жизни записи в кэше
+func (e Entry) Remaining() time.Duration {
+	if r := time.Until(e.ExpiresAt()); r > 0 {
+		return r
+	}
+	return 0
+}
+
+func (e Entry) expired(now time.Time) bool {
+	return now.Sub(e.FetchedAt) > e.TTL
+}
+
+// prepare - заполнить время получения и TTL по умолчанию перед сохранением
+func (e Entry) prepare(defaultTTL time.Duration) Entry {
+	if e.FetchedAt.IsZero() {
+		e.FetchedAt = time.Now()
+	}
+	if e.TTL <= 0 {
+		e.TTL = defaultTTL
+	}
+	return e
+}
+
+func marshalEntry(e Entry) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func unmarshalEntry(b []byte) (Entry, error) {
+	var e Entry
+	err := json.Unmarshal(b, &e)
+	return e, err
+}
+
+func (c *counters) hit()     { atomic.AddUint64(&c.hits, 1) }
+func (c *counters) miss()    { atomic.AddUint64(&c.misses, 1) }
+func (c *counters) expire()  { atomic.AddUint64(&c.expired, 1) }
+func (c *counters) evicted() { atomic.AddUint64(&c.evictions, 1) }
+
+func (c *counters) stats() Stats {
+	return Stats{
+		Entries:   -1,
+		Bytes:     -1,
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Expired:   atomic.LoadUint64(&c.expired),
+		Evictions: atomic.LoadUint64(&c.evictions),
+	}
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"crypto/sha1" //nolint:gosec // используется только для имени файла
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const diskEntryExt = ".json"
+
+type (
+	// DiskStorage - встроенное key-value хранилище на диске: одна запись - один файл в каталоге.
+	// Переживает рестарт сервиса, запись атомарная (tmp файл + rename).
+	DiskStorage struct {
+		counters // первым полем: выравнивание 64-bit atomic на 32-bit платформах
+
+		dir string
+		ttl time.Duration
+
+		stop chan struct{}
+		once sync.Once
+	}
+
+	diskRecord struct {
+		FQDN  FQDN  `json:"fqdn"`
+		Entry Entry `json:"entry"`
+	}
+)
+
+var _ Cache = (*DiskStorage)(nil)
+
+func NewDisk(dir string, ttl, sweepInterval time.Duration) (*DiskStorage, error) {
+	if dir == "" {
+		return nil, errors.New("disk cache path is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WithMessagef(err, "can't create disk cache dir %s", dir)
+	}
+
+	// "защита от дурака"
+	if ttl <= 0 {
+		ttl = TTLCacheDefault
+	}
+	if sweepInterval <= 0 {
+		sweepInterval = SweepIntervalDefault
+	}
+
+	d := &DiskStorage{
+		dir:  dir,
+		ttl:  ttl,
+		stop: make(chan struct{}),
+	}
+
+	go d.janitor(sweepInterval)
+
+	return d, nil
+}
+
+func (d *DiskStorage) GetEntry(fqdn FQDN) (Entry, bool, error) {
+	path := d.path(fqdn)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		d.miss()
+		if os.IsNotExist(err) {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, errors.WithMessagef(err, "can't read disk cache entry for %s", fqdn)
+	}
+
+	var rec diskRecord
+	if err := json.Unmarshal(b, &rec); err != nil || rec.FQDN != fqdn {
+		_ = os.Remove(path) // битая запись
+		d.miss()
+		return Entry{}, false, nil
+	}
+
+	if rec.Entry.expired(time.Now()) {
+		_ = os.Remove(path)
+		d.expire()
+		d.miss()
+		return Entry{}, false, nil
+	}
+
+	d.hit()
+	return rec.Entry, true, nil
+}
+
+func (d *DiskStorage) Stats() Stats {
+	return d.stats()
+}
+
+func (d *DiskStorage) SetEntry(fqdn FQDN, entry Entry) error {
+	b, err := json.Marshal(diskRecord{FQDN: fqdn, Entry: entry.prepare(d.ttl)})
+	if err != nil {
+		return errors.WithMessagef(err, "can't marshal cache entry for %s", fqdn)
+	}
+
+	tmp, err := ioutil.TempFile(d.dir, ".tmp-")
+	if err != nil {
+		return errors.WithMessage(err, "can't create disk cache tmp file")
+	}
+
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.path(fqdn))
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WithMessagef(err, "can't write disk cache entry for %s", fqdn)
+	}
+
+	return nil
+}
+
+func (d *DiskStorage) Remove(fqdn FQDN) error {
+	if err := os.Remove(d.path(fqdn)); err != nil && !os.IsNotExist(err) {
+		return errors.WithMessagef(err, "can't remove disk cache entry for %s", fqdn)
+	}
+	return nil
+}
+
+func (d *DiskStorage) RemoveAll() error {
+	return d.walk(func(path string) bool { return true })
+}
+
+func (d *DiskStorage) Close() error {
+	d.once.Do(func() { close(d.stop) })
+	return nil
+}
+
+func (d *DiskStorage) path(fqdn FQDN) string {
+	sum := sha1.Sum([]byte(fqdn)) //nolint:gosec
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+diskEntryExt)
+}
+
+func (d *DiskStorage) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			_ = d.sweep()
+		}
+	}
+}
+
+// sweep - удалить просроченные и битые записи
+func (d *DiskStorage) sweep() error {
+	now := time.Now()
+	return d.walk(func(path string) bool {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return false
+		}
+
+		var rec diskRecord
+		if json.Unmarshal(b, &rec) != nil {
+			return true
+		}
+		if rec.Entry.expired(now) {
+			d.expire()
+			return true
+		}
+		return false
+	})
+}
+
+// walk - обход файлов записей каталога, удаляются файлы для которых remove вернул true
+func (d *DiskStorage) walk(remove func(path string) bool) error {
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return errors.WithMessagef(err, "can't read disk cache dir %s", d.dir)
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), diskEntryExt) {
+			continue
+		}
+
+		path := filepath.Join(d.dir, f.Name())
+		if remove(path) {
+			_ = os.Remove(path)
+		}
+	}
+
+	return nil
+}
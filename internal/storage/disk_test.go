@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskStorage_SetAndGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-disk-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := NewDisk(dir, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("disk storage not created: %v", err)
+	}
+
+	entry := Entry{Raw: "whois info", Upstream: "whois.tcinet.ru:43", Class: "notfound"}
+	if err := d.SetEntry("test.domain.ru", entry); err != nil {
+		t.Fatalf("can't set entry: %v", err)
+	}
+	_ = d.Close()
+
+	// новое хранилище в том же каталоге видит записи предыдущего
+	d, err = NewDisk(dir, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("disk storage not created: %v", err)
+	}
+	defer d.Close()
+
+	got, found, err := d.GetEntry("test.domain.ru")
+	if err != nil || !found {
+		t.Fatalf("not found entry: %v", err)
+	}
+	if got.Raw != entry.Raw || got.Upstream != entry.Upstream || got.Class != entry.Class ||
+		got.TTL != time.Minute || got.FetchedAt.IsZero() {
+		t.Errorf("get entry non equals set entry: %+v", got)
+	}
+
+	if err := d.Remove("test.domain.ru"); err != nil {
+		t.Errorf("can't remove entry: %v", err)
+	}
+	if _, found, _ := d.GetEntry("test.domain.ru"); found {
+		t.Errorf("found removed entry")
+	}
+}
+
+func TestDiskStorage_TTLAndSweep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-disk-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := NewDisk(dir, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("disk storage not created: %v", err)
+	}
+	defer d.Close()
+
+	_ = d.SetEntry("short.domain.ru", Entry{Raw: "whois", TTL: time.Millisecond})
+	_ = d.SetEntry("alive.domain.ru", Entry{Raw: "whois"})
+	_ = ioutil.WriteFile(filepath.Join(dir, "broken"+diskEntryExt), []byte("{"), 0644)
+
+	time.Sleep(time.Millisecond * 10)
+	if err := d.sweep(); err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("unexpected files count after sweep: %d", len(files))
+	}
+
+	if err := d.RemoveAll(); err != nil {
+		t.Errorf("can't remove all entries: %v", err)
+	}
+	if _, found, _ := d.GetEntry("alive.domain.ru"); found {
+		t.Errorf("found entry after RemoveAll")
+	}
+}
@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RedisPrefixDefault   = "whois-proxy:"
+	RedisTimeoutDefault  = time.Second
+	RedisPoolSizeDefault = 16
+)
+
+type (
+	// RedisStorage - кэш в redis, общий для нескольких реплик whois-proxy.
+	// Реализован минимальный RESP клиент (GET/SET/DEL/SCAN), чтобы не тянуть внешних зависимостей.
+	RedisStorage struct {
+		counters // первым полем: выравнивание 64-bit atomic на 32-bit платформах
+
+		opts RedisOptions
+		pool chan *redisConn
+	}
+
+	RedisOptions struct {
+		Addr     string
+		Password string
+		DB       int
+		Prefix   string
+		Timeout  time.Duration
+		PoolSize int
+		TTL      time.Duration // TTL для записей без собственного TTL
+	}
+
+	redisConn struct {
+		conn net.Conn
+		r    *bufio.Reader
+	}
+
+	redisError string
+)
+
+var _ Cache = (*RedisStorage)(nil)
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func NewRedis(opts RedisOptions) (*RedisStorage, error) {
+	if opts.Addr == "" {
+		return nil, errors.New("redis address is empty")
+	}
+
+	// "защита от дурака"
+	if opts.Prefix == "" {
+		opts.Prefix = RedisPrefixDefault
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = RedisTimeoutDefault
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = RedisPoolSizeDefault
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = TTLCacheDefault
+	}
+
+	r := &RedisStorage{
+		opts: opts,
+		pool: make(chan *redisConn, opts.PoolSize),
+	}
+
+	// проверка доступности redis при старте
+	if _, err := r.do("PING"); err != nil {
+		return nil, errors.WithMessagef(err, "redis %s unavailable", opts.Addr)
+	}
+
+	return r, nil
+}
+
+func (r *RedisStorage) GetEntry(fqdn FQDN) (Entry, bool, error) {
+	reply, err := r.do("GET", r.key(fqdn))
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	b, ok := reply.([]byte)
+	if !ok { // nil reply - ключа нет
+		r.miss()
+		return Entry{}, false, nil
+	}
+
+	entry, err := unmarshalEntry(b)
+	if err != nil {
+		r.miss()
+		return Entry{}, false, errors.WithMessagef(err, "bad cache entry for %s", fqdn)
+	}
+
+	if entry.expired(time.Now()) {
+		r.expire()
+		r.miss()
+		return Entry{}, false, nil
+	}
+
+	r.hit()
+	return entry, true, nil
+}
+
+func (r *RedisStorage) Stats() Stats {
+	return r.stats()
+}
+
+func (r *RedisStorage) SetEntry(fqdn FQDN, entry Entry) error {
+	entry = entry.prepare(r.opts.TTL)
+
+	ttl := time.Until(entry.ExpiresAt())
+	if ttl <= 0 {
+		return nil
+	}
+
+	b, err := marshalEntry(entry)
+	if err != nil {
+		return errors.WithMessagef(err, "can't marshal cache entry for %s", fqdn)
+	}
+
+	ms := ttl.Milliseconds()
+	if ms == 0 {
+		ms = 1
+	}
+
+	_, err = r.do("SET", r.key(fqdn), string(b), "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+func (r *RedisStorage) Remove(fqdn FQDN) error {
+	_, err := r.do("DEL", r.key(fqdn))
+	return err
+}
+
+// RemoveAll - удалить все ключи с префиксом whois-proxy (SCAN + DEL, без FLUSHDB)
+func (r *RedisStorage) RemoveAll() error {
+	cursor := "0"
+	for {
+		reply, err := r.do("SCAN", cursor, "MATCH", r.opts.Prefix+"*", "COUNT", "100")
+		if err != nil {
+			return err
+		}
+
+		arr, ok := reply.([]interface{})
+		if !ok || len(arr) != 2 {
+			return redisError("unexpected SCAN reply")
+		}
+
+		next, _ := arr[0].([]byte)
+		keys, _ := arr[1].([]interface{})
+		if len(keys) > 0 {
+			args := make([]string, 0, len(keys)+1)
+			args = append(args, "DEL")
+			for _, k := range keys {
+				if b, ok := k.([]byte); ok {
+					args = append(args, string(b))
+				}
+			}
+			if _, err := r.do(args...); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+func (r *RedisStorage) Close() error {
+	for {
+		select {
+		case c := <-r.pool:
+			_ = c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (r *RedisStorage) key(fqdn FQDN) string {
+	return r.opts.Prefix + fqdn
+}
+
+// do - выполнить команду на соединении из пула
+func (r *RedisStorage) do(args ...string) (interface{}, error) {
+	c, err := r.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(r.opts.Timeout, args...)
+	if err != nil {
+		if _, ok := err.(redisError); !ok { // сетевая ошибка - соединение больше не используем
+			_ = c.conn.Close()
+			return nil, errors.WithMessagef(err, "redis %s", args[0])
+		}
+	}
+
+	r.putConn(c)
+
+	return reply, err
+}
+
+func (r *RedisStorage) getConn() (*redisConn, error) {
+	select {
+	case c := <-r.pool:
+		return c, nil
+	default:
+	}
+
+	conn, err := net.DialTimeout("tcp", r.opts.Addr, r.opts.Timeout)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't connect to redis %s", r.opts.Addr)
+	}
+
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if r.opts.Password != "" {
+		if _, err := c.do(r.opts.Timeout, "AUTH", r.opts.Password); err != nil {
+			_ = conn.Close()
+			return nil, errors.WithMessage(err, "redis AUTH")
+		}
+	}
+
+	if r.opts.DB != 0 {
+		if _, err := c.do(r.opts.Timeout, "SELECT", strconv.Itoa(r.opts.DB)); err != nil {
+			_ = conn.Close()
+			return nil, errors.WithMessage(err, "redis SELECT")
+		}
+	}
+
+	return c, nil
+}
+
+func (r *RedisStorage) putConn(c *redisConn) {
+	select {
+	case r.pool <- c:
+	default: // пул заполнен
+		_ = c.conn.Close()
+	}
+}
+
+func (c *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+
+	return readRESP(c.r)
+}
+
+// readRESP - чтение ответа redis: +simple, -error, :integer, $bulk, *array
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := readRESP(r)
+			if err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}
+
+	return nil, errors.Errorf("redis: unknown reply %q", line)
+}
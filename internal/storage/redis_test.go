@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis - минимальная замена redis-server (PING, AUTH, SELECT, GET, SET PX, DEL, SCAN) для тестов
+// Команды разбираются своим кодом, не readRESP, чтобы ошибка кодирования RESP клиентом не прошла незамеченной.
+type fakeRedis struct {
+	t  *testing.T
+	l  net.Listener
+	mu sync.Mutex
+	m  map[string]fakeRedisValue
+
+	protoErr error // первая ошибка формата команды, проверяется в Close
+}
+
+type fakeRedisValue struct {
+	v       string
+	expires time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+
+	f := &fakeRedis{t: t, l: l, m: map[string]fakeRedisValue{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeRedis) Addr() string { return f.l.Addr().String() }
+
+func (f *fakeRedis) Close() {
+	_ = f.l.Close()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.protoErr != nil {
+		f.t.Errorf("bad command from client: %v", f.protoErr)
+	}
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readFakeCommand(r)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			f.mu.Lock()
+			if f.protoErr == nil {
+				f.protoErr = err
+			}
+			f.mu.Unlock()
+			_, _ = io.WriteString(conn, "-ERR Protocol error\r\n")
+			return
+		}
+
+		if _, err := io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readFakeCommand - команда клиента строго по RESP: "*<n>\r\n", затем n раз "$<len>\r\n<len байт>\r\n"
+func readFakeCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readFakeHeader(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("bad array length %d", n)
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		size, err := readFakeHeader(r, '$')
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if string(buf[size:]) != "\r\n" {
+			return nil, fmt.Errorf("bulk string %q is not terminated by CRLF", buf)
+		}
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+func readFakeHeader(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		if line == "" {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(line) < 4 || line[0] != prefix || !strings.HasSuffix(line, "\r\n") {
+		return 0, fmt.Errorf("bad header %q, want %q<n>\\r\\n", line, prefix)
+	}
+	n, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad header %q", line)
+	}
+	return n, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.m[args[1]]
+		if !ok || !v.expires.IsZero() && time.Now().After(v.expires) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v.v), v.v)
+	case "SET":
+		v := fakeRedisValue{v: args[2]}
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			v.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		f.m[args[1]] = v
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if _, ok := f.m[k]; ok {
+				delete(f.m, k)
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SCAN":
+		prefix := strings.TrimSuffix(args[3], "*")
+		var keys []string
+		for k := range f.m {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, fmt.Sprintf("$%d\r\n%s\r\n", len(k), k))
+			}
+		}
+		return fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n%s", len(keys), strings.Join(keys, ""))
+	}
+
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisStorage_SetAndGet(t *testing.T) {
+	fake := newFakeRedis(t)
+	defer fake.Close()
+
+	r, err := NewRedis(RedisOptions{Addr: fake.Addr(), Password: "secret", DB: 1})
+	if err != nil {
+		t.Fatalf("redis storage not created: %v", err)
+	}
+	defer r.Close()
+
+	fetched := time.Now().Add(-time.Second).Round(0)
+	entry := Entry{
+		Raw:       strings.Repeat("whois\r\n", 100),
+		FetchedAt: fetched,
+		TTL:       time.Minute,
+		Upstream:  "whois.tcinet.ru:43",
+		Class:     "found",
+	}
+
+	if err := r.SetEntry("test.domain.ru", entry); err != nil {
+		t.Fatalf("can't set entry: %v", err)
+	}
+
+	got, found, err := r.GetEntry("test.domain.ru")
+	if err != nil || !found {
+		t.Fatalf("not found entry: %v", err)
+	}
+
+	if got.Raw != entry.Raw || !got.FetchedAt.Equal(fetched) || got.TTL != entry.TTL ||
+		got.Upstream != entry.Upstream || got.Class != entry.Class {
+		t.Errorf("get entry non equals set entry: %+v", got)
+	}
+
+	if _, found, _ := r.GetEntry("unknown.domain.ru"); found {
+		t.Errorf("found unknown entry")
+	}
+}
+
+func TestRedisStorage_RemoveAndTTL(t *testing.T) {
+	fake := newFakeRedis(t)
+	defer fake.Close()
+
+	r, err := NewRedis(RedisOptions{Addr: fake.Addr(), TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("redis storage not created: %v", err)
+	}
+	defer r.Close()
+
+	_ = r.SetEntry("short.domain.ru", Entry{Raw: "whois", TTL: time.Millisecond * 50})
+	_ = r.SetEntry("test1.domain.ru", Entry{Raw: "whois"})
+	_ = r.SetEntry("test2.domain.ru", Entry{Raw: "whois"})
+
+	time.Sleep(time.Millisecond * 100)
+	if _, found, _ := r.GetEntry("short.domain.ru"); found {
+		t.Errorf("not remove (TTL) entry short.domain.ru")
+	}
+
+	if err := r.Remove("test1.domain.ru"); err != nil {
+		t.Errorf("can't remove entry: %v", err)
+	}
+	if _, found, _ := r.GetEntry("test1.domain.ru"); found {
+		t.Errorf("found removed entry test1.domain.ru")
+	}
+
+	if err := r.RemoveAll(); err != nil {
+		t.Errorf("can't remove all entries: %v", err)
+	}
+	if _, found, _ := r.GetEntry("test2.domain.ru"); found {
+		t.Errorf("found entry test2.domain.ru after RemoveAll")
+	}
+}
+
+func TestRedisConn_Wire(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+
+		want := "*5\r\n$3\r\nSET\r\n$7\r\nwhois:a\r\n$6\r\nx\r\ny z\r\n$2\r\nPX\r\n$4\r\n1500\r\n"
+		buf := make([]byte, len(want))
+		if _, err := io.ReadFull(server, buf); err != nil || string(buf) != want {
+			t.Errorf("unexpected command bytes %q (%v), want %q", buf, err, want)
+		}
+		_, _ = io.WriteString(server, "*2\r\n$1\r\n0\r\n*2\r\n$7\r\nwhois:a\r\n$-1\r\n")
+	}()
+
+	c := &redisConn{conn: client, r: bufio.NewReader(client)}
+	reply, err := c.do(time.Second, "SET", "whois:a", "x\r\ny z", "PX", "1500")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	arr, ok := reply.([]interface{})
+	if !ok || len(arr) != 2 || string(arr[0].([]byte)) != "0" {
+		t.Fatalf("unexpected reply: %#v", reply)
+	}
+	keys, ok := arr[1].([]interface{})
+	if !ok || len(keys) != 2 || string(keys[0].([]byte)) != "whois:a" || keys[1] != nil {
+		t.Errorf("unexpected reply: %#v", reply)
+	}
+}
+
+func TestRedisStorage_Negative(t *testing.T) {
+	if _, err := NewRedis(RedisOptions{}); err == nil {
+		t.Errorf("no error for empty address")
+	}
+
+	if _, err := NewRedis(RedisOptions{Addr: "127.0.0.1:1", Timeout: time.Millisecond * 100}); err == nil {
+		t.Errorf("no error for unavailable redis")
+	}
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Формат snapshot: magic (6 байт) | version (uint16) | gob([]snapshotEntry) | crc32(gob) (uint32)
+const (
+	snapshotMagic   = "WPSNAP"
+	SnapshotVersion = 1
+)
+
+var (
+	ErrSnapshotCorrupt = errors.New("snapshot is corrupt")
+	ErrSnapshotVersion = errors.New("snapshot version mismatch")
+)
+
+type snapshotEntry struct {
+	FQDN  FQDN
+	Entry Entry
+}
+
+// SaveSnapshot - атомарно (tmp файл + rename) записать snapshot кэша в файл
+func (c *WhoisDataStorage) SaveSnapshot(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return errors.WithMessage(err, "can't create snapshot tmp file")
+	}
+
+	if err = c.WriteSnapshot(tmp); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WithMessagef(err, "can't save snapshot %s", path)
+	}
+
+	return nil
+}
+
+// LoadSnapshot - загрузить snapshot из файла, возвращает число загруженных записей
+func (c *WhoisDataStorage) LoadSnapshot(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return c.ReadSnapshot(f)
+}
+
+// WriteSnapshot - записать не просроченные записи (от least к most recently used) вместе с временем получения
+func (c *WhoisDataStorage) WriteSnapshot(w io.Writer) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	entries := make([]snapshotEntry, 0, c.lru.Len())
+	for e := c.lru.Back(); e != nil; e = e.Prev() {
+		data := e.Value.(*whoisData)
+		if !data.entry.expired(now) {
+			entries = append(entries, snapshotEntry{FQDN: data.fqdn, Entry: data.entry})
+		}
+	}
+	c.mu.Unlock()
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(entries); err != nil {
+		return errors.WithMessage(err, "can't encode snapshot")
+	}
+
+	header := make([]byte, len(snapshotMagic)+2)
+	copy(header, snapshotMagic)
+	binary.BigEndian.PutUint16(header[len(snapshotMagic):], SnapshotVersion)
+
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(payload.Bytes()))
+
+	for _, b := range [][]byte{header, payload.Bytes(), checksum} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadSnapshot - загрузить записи snapshot, просроченные за время простоя записи пропускаются.
+// Битый snapshot или snapshot другой версии не загружается целиком.
+func (c *WhoisDataStorage) ReadSnapshot(r io.Reader) (int, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, errors.WithMessage(err, "can't read snapshot")
+	}
+
+	headerLen := len(snapshotMagic) + 2
+	if len(b) < headerLen+4 || string(b[:len(snapshotMagic)]) != snapshotMagic {
+		return 0, ErrSnapshotCorrupt
+	}
+
+	if v := binary.BigEndian.Uint16(b[len(snapshotMagic):headerLen]); v != SnapshotVersion {
+		return 0, errors.WithMessagef(ErrSnapshotVersion, "got %d, expected %d", v, SnapshotVersion)
+	}
+
+	payload, checksum := b[headerLen:len(b)-4], b[len(b)-4:]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(checksum) {
+		return 0, ErrSnapshotCorrupt
+	}
+
+	var entries []snapshotEntry
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&entries); err != nil {
+		return 0, errors.WithMessage(ErrSnapshotCorrupt, err.Error())
+	}
+
+	now := time.Now()
+	restored := make([]FQDN, 0, len(entries))
+	for _, e := range entries {
+		if e.Entry.FetchedAt.IsZero() || e.Entry.expired(now) {
+			continue
+		}
+		_ = c.SetEntry(e.FQDN, e.Entry)
+		restored = append(restored, e.FQDN)
+	}
+
+	// считаются только оставшиеся в кэше: записи больше MaxBytes не сохраняются, следующие вытесняют предыдущие
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loaded, seen := 0, make(map[FQDN]bool, len(restored))
+	for _, fqdn := range restored {
+		if _, found := c.m[fqdn]; found && !seen[fqdn] {
+			seen[fqdn] = true
+			loaded++
+		}
+	}
+
+	return loaded, nil
+}
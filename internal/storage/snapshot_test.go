@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestWhoisDataStorage_Snapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache.snapshot")
+
+	storage := New(time.Second*100, time.Hour)
+	defer storage.Close()
+
+	fetched := time.Now().Add(-time.Second * 10)
+	_ = storage.SetEntry("test.domain.ru", Entry{Raw: "whois info", FetchedAt: fetched, Upstream: "whois.tcinet.ru:43"})
+	_ = storage.SetEntry("expired.domain.ru", Entry{Raw: "whois info", FetchedAt: fetched, TTL: time.Second})
+	_ = storage.SetEntry("short.domain.ru", Entry{Raw: "whois info", TTL: time.Millisecond * 100})
+
+	if err := storage.SaveSnapshot(path); err != nil {
+		t.Fatalf("can't save snapshot: %v", err)
+	}
+
+	time.Sleep(time.Millisecond * 150) // short.domain.ru устарел пока сервис "лежал"
+
+	restored := New(time.Second*100, time.Hour)
+	defer restored.Close()
+
+	loaded, err := restored.LoadSnapshot(path)
+	if err != nil || loaded != 1 {
+		t.Fatalf("unexpected snapshot load result: %d %v", loaded, err)
+	}
+
+	entry, found, _ := restored.GetEntry("test.domain.ru")
+	if !found || entry.Raw != "whois info" || !entry.FetchedAt.Equal(fetched) || entry.Upstream != "whois.tcinet.ru:43" {
+		t.Errorf("unexpected restored entry: %+v", entry)
+	}
+
+	for _, fqdn := range []string{"expired.domain.ru", "short.domain.ru"} {
+		if _, found := restored.Get(fqdn); found {
+			t.Errorf("restored expired entry %s", fqdn)
+		}
+	}
+}
+
+func TestWhoisDataStorage_Snapshot_MaxBytes(t *testing.T) {
+	storage := New(time.Second*100, time.Hour)
+	defer storage.Close()
+
+	for _, fqdn := range []string{"a.ru", "b.ru", "c.ru"} {
+		_ = storage.SetEntry(fqdn, Entry{Raw: strings.Repeat("x", 40), FetchedAt: time.Now()})
+	}
+	_ = storage.SetEntry("huge.ru", Entry{Raw: strings.Repeat("h", 1000), FetchedAt: time.Now()})
+
+	var buf bytes.Buffer
+	if err := storage.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("can't write snapshot: %v", err)
+	}
+
+	// в кэш помещаются две записи по 40 байт, huge.ru не помещается совсем
+	restored := NewWithOptions(Options{TTL: time.Second * 100, MaxBytes: 100})
+	defer restored.Close()
+
+	loaded, err := restored.ReadSnapshot(&buf)
+	if err != nil || loaded != restored.Len() || loaded != 2 {
+		t.Errorf("unexpected snapshot load result: %d (%d in cache) %v", loaded, restored.Len(), err)
+	}
+}
+
+func TestWhoisDataStorage_Snapshot_Negative(t *testing.T) {
+	storage := New(time.Second*100, time.Hour)
+	defer storage.Close()
+	storage.Set("test.domain.ru", "whois info")
+
+	var buf bytes.Buffer
+	if err := storage.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("can't write snapshot: %v", err)
+	}
+	good := buf.Bytes()
+
+	corrupt := append([]byte{}, good...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	version := append([]byte{}, good...)
+	version[len(snapshotMagic)+1]++
+
+	testCases := []struct {
+		data []byte
+		err  error
+	}{
+		{[]byte{}, ErrSnapshotCorrupt},
+		{[]byte("not a snapshot at all"), ErrSnapshotCorrupt},
+		{corrupt, ErrSnapshotCorrupt},
+		{good[:len(good)-1], ErrSnapshotCorrupt},
+		{version, ErrSnapshotVersion},
+	}
+
+	for n, test := range testCases {
+		restored := New(time.Second*100, time.Hour)
+		loaded, err := restored.ReadSnapshot(bytes.NewReader(test.data))
+		if errors.Cause(err) != test.err || loaded != 0 || restored.Len() != 0 {
+			t.Errorf("unexpected result for test case #%d: %d %v", n, loaded, err)
+		}
+		_ = restored.Close()
+	}
+}
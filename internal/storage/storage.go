@@ -1,81 +1,293 @@
 package storage
 
 import (
+	"container/list"
+	"sort"
 	"sync"
 	"time"
 )
 
 const (
-	TTLCacheResetDefault = 24 * time.Hour
+	SweepIntervalDefault = time.Minute
 	TTLCacheDefault      = 5 * time.Minute
+
+	// за один проход janitor проверяет sweepSampleSize случайных записей и повторяет проход,
+	// пока доля просроченных больше sweepRepeatRatio (но не более sweepMaxRounds раз)
+	sweepSampleSize  = 20
+	sweepRepeatRatio = 0.25
+	sweepMaxRounds   = 16
 )
 
 type (
 	WhoisDataStorage struct {
-		TTL time.Duration
-		mu  sync.Mutex
-		m   map[FQDN]whoisData
+		counters // первым полем: выравнивание 64-bit atomic на 32-bit платформах
+
+		TTL        time.Duration
+		MaxEntries int   // 0 - без ограничения
+		MaxBytes   int64 // 0 - без ограничения
+
+		mu    sync.Mutex
+		m     map[FQDN]*list.Element
+		lru   *list.List // front - most recently used
+		bytes int64
+
+		stop chan struct{}
+		once sync.Once
+	}
+
+	// Options - параметры хранилища для NewWithOptions
+	Options struct {
+		TTL           time.Duration
+		SweepInterval time.Duration
+		MaxEntries    int
+		MaxBytes      int64
 	}
 
 	FQDN = string
 
 	whoisData struct {
-		raw  string
-		time time.Time
+		fqdn  FQDN
+		entry Entry
+		hits  uint64 // число обращений к записи с момента сохранения
 	}
 )
 
-func New(ttl, autoCleanTimeout time.Duration) *WhoisDataStorage {
+var _ Cache = (*WhoisDataStorage)(nil)
+
+func New(ttl, sweepInterval time.Duration) *WhoisDataStorage {
+	return NewWithOptions(Options{TTL: ttl, SweepInterval: sweepInterval})
+}
+
+func NewWithOptions(opts Options) *WhoisDataStorage {
 	// "защита от дурака"
-	if autoCleanTimeout == 0 {
-		autoCleanTimeout = TTLCacheResetDefault
+	if opts.SweepInterval <= 0 {
+		opts.SweepInterval = SweepIntervalDefault
 	}
 
-	if ttl == 0 {
-		ttl = TTLCacheDefault
+	if opts.TTL <= 0 {
+		opts.TTL = TTLCacheDefault
 	}
 
 	storage := &WhoisDataStorage{
-		TTL: ttl,
-		mu:  sync.Mutex{},
-		m:   map[FQDN]whoisData{},
+		TTL:        opts.TTL,
+		MaxEntries: opts.MaxEntries,
+		MaxBytes:   opts.MaxBytes,
+		mu:         sync.Mutex{},
+		m:          map[FQDN]*list.Element{},
+		lru:        list.New(),
+		stop:       make(chan struct{}),
 	}
 
-	// запуск горутины которая раз в autoCleanTimeout полностью сбрасывае кэш
-	go func() {
-		for {
-			time.Sleep(autoCleanTimeout)
-			storage.RemoveAll()
-		}
-	}()
+	// запуск горутины которая раз в sweepInterval понемногу удаляет просроченные записи
+	go storage.janitor(opts.SweepInterval)
 
 	return storage
 }
 
-func (c *WhoisDataStorage) RemoveAll() {
+// Close - остановка фоновой очистки хранилища
+func (c *WhoisDataStorage) Close() error {
+	c.once.Do(func() { close(c.stop) })
+	return nil
+}
+
+func (c *WhoisDataStorage) RemoveAll() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.m = map[FQDN]whoisData{}
+	c.m = map[FQDN]*list.Element{}
+	c.lru.Init()
+	c.bytes = 0
+
+	return nil
 }
 
 func (c *WhoisDataStorage) Get(fqdn FQDN) (string, bool) {
+	entry, ok, _ := c.GetEntry(fqdn)
+	return entry.Raw, ok
+}
+
+func (c *WhoisDataStorage) GetEntry(fqdn FQDN) (Entry, bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	data, ok := c.m[fqdn]
-	if ok && time.Since(data.time) > c.TTL { // auto remove too old data from cache
-		delete(c.m, fqdn)
-		ok = false
-		data.raw = ""
+	e, ok := c.m[fqdn]
+	if !ok {
+		c.miss()
+		return Entry{}, false, nil
+	}
+
+	data := e.Value.(*whoisData)
+	if data.entry.expired(time.Now()) { // auto remove too old data from cache
+		c.remove(e)
+		c.expire()
+		c.miss()
+		return Entry{}, false, nil
 	}
 
-	return data.raw, ok
+	c.lru.MoveToFront(e)
+	c.hit()
+	data.hits++
+
+	return data.entry, true, nil
 }
 
 func (c *WhoisDataStorage) Set(fqdn FQDN, whois string) {
+	c.SetWithTTL(fqdn, whois, 0)
+}
+
+// SetWithTTL - сохранить whois с собственным TTL записи (ttl == 0 - используется общий TTL хранилища)
+func (c *WhoisDataStorage) SetWithTTL(fqdn FQDN, whois string, ttl time.Duration) {
+	_ = c.SetEntry(fqdn, Entry{Raw: whois, TTL: ttl})
+}
+
+func (c *WhoisDataStorage) SetEntry(fqdn FQDN, entry Entry) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.m[fqdn] = whoisData{whois, time.Now()}
+	if e, found := c.m[fqdn]; found {
+		c.remove(e)
+	}
+
+	data := &whoisData{fqdn: fqdn, entry: entry.prepare(c.TTL)}
+	if c.MaxBytes > 0 && data.size() > c.MaxBytes { // запись больше всего кэша - не храним
+		return nil
+	}
+
+	c.m[fqdn] = c.lru.PushFront(data)
+	c.bytes += data.size()
+
+	c.evict()
+
+	return nil
+}
+
+func (c *WhoisDataStorage) Remove(fqdn FQDN) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, found := c.m[fqdn]; found {
+		c.remove(e)
+	}
+
+	return nil
+}
+
+func (c *WhoisDataStorage) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *WhoisDataStorage) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats()
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+
+	return stats
+}
+
+// HotEntries - ключи популярных записей (не менее minHits обращений), которые устареют в течение window.
+// Отсортированы по убыванию числа обращений, не более limit штук (0 - без ограничения).
+func (c *WhoisDataStorage) HotEntries(minHits uint64, window time.Duration, limit int) []FQDN {
+	type hotEntry struct {
+		fqdn FQDN
+		hits uint64
+	}
+
+	now := time.Now()
+	deadline := now.Add(window)
+
+	c.mu.Lock()
+	hot := make([]hotEntry, 0)
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		data := e.Value.(*whoisData)
+		if data.hits >= minHits && data.entry.ExpiresAt().Before(deadline) && !data.entry.expired(now) {
+			hot = append(hot, hotEntry{fqdn: data.fqdn, hits: data.hits})
+		}
+	}
+	c.mu.Unlock()
+
+	sort.SliceStable(hot, func(i, j int) bool { return hot[i].hits > hot[j].hits })
+	if limit > 0 && len(hot) > limit {
+		hot = hot[:limit]
+	}
+
+	keys := make([]FQDN, 0, len(hot))
+	for _, e := range hot {
+		keys = append(keys, e.fqdn)
+	}
+	return keys
+}
+
+// evict - вытеснение least recently used записей при превышении лимитов. Вызывать под mutex.
+func (c *WhoisDataStorage) evict() {
+	for c.lru.Len() > 0 &&
+		(c.MaxEntries > 0 && c.lru.Len() > c.MaxEntries || c.MaxBytes > 0 && c.bytes > c.MaxBytes) {
+		c.remove(c.lru.Back())
+		c.evicted()
+	}
+}
+
+// remove - удалить запись. Вызывать под mutex.
+func (c *WhoisDataStorage) remove(e *list.Element) {
+	data := c.lru.Remove(e).(*whoisData)
+	delete(c.m, data.fqdn)
+	c.bytes -= data.size()
+}
+
+func (c *WhoisDataStorage) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.sweep()
+		}
+	}
+}
+
+// sweep - инкрементальная очистка просроченных записей по случайной выборке (как в redis),
+// вместо полного сброса кэша
+func (c *WhoisDataStorage) sweep() {
+	for round := 0; round < sweepMaxRounds; round++ {
+		if c.sweepSample() <= sweepRepeatRatio {
+			return
+		}
+	}
+}
+
+func (c *WhoisDataStorage) sweepSample() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	checked, expired := 0, 0
+	for _, e := range c.m { // порядок обхода map случайный
+		if checked == sweepSampleSize {
+			break
+		}
+		checked++
+
+		if e.Value.(*whoisData).entry.expired(now) {
+			c.remove(e)
+			c.expire()
+			expired++
+		}
+	}
+
+	if checked == 0 {
+		return 0
+	}
+	return float64(expired) / float64(checked)
+}
+
+func (d *whoisData) size() int64 {
+	return int64(len(d.fqdn) + len(d.entry.Raw) + len(d.entry.Upstream))
 }
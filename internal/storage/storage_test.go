@@ -167,3 +167,146 @@ func TestWhoisDataStorage_GoRoutinSafe(t *testing.T) {
 
 	close(stop)
 }
+
+func TestWhoisDataStorage_SetWithTTL(t *testing.T) {
+	const TTL = time.Second * 100
+	storage := New(TTL, time.Hour*24)
+
+	storage.SetWithTTL("short.domain.ru", "short ttl whois info", time.Millisecond*100)
+	storage.SetWithTTL("default.domain.ru", "default ttl whois info", 0)
+
+	time.Sleep(time.Millisecond * 200)
+
+	if _, found := storage.Get("short.domain.ru"); found {
+		t.Errorf("not remove (entry TTL) whois text for domain short.domain.ru")
+	}
+
+	if _, found := storage.Get("default.domain.ru"); !found {
+		t.Errorf("not found whois text for domain default.domain.ru")
+	}
+}
+
+func TestWhoisDataStorage_LRU_MaxEntries(t *testing.T) {
+	storage := NewWithOptions(Options{TTL: time.Second * 100, MaxEntries: 2})
+	defer storage.Close()
+
+	storage.Set("test1.domain.ru", "whois info 1")
+	storage.Set("test2.domain.ru", "whois info 2")
+	storage.Get("test1.domain.ru") // test2 now least recently used
+	storage.Set("test3.domain.ru", "whois info 3")
+
+	if _, found := storage.Get("test2.domain.ru"); found {
+		t.Errorf("least recently used entry test2.domain.ru not evicted")
+	}
+
+	for _, fqdn := range []string{"test1.domain.ru", "test3.domain.ru"} {
+		if _, found := storage.Get(fqdn); !found {
+			t.Errorf("not found whois text for domain %s", fqdn)
+		}
+	}
+
+	if stats := storage.Stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestWhoisDataStorage_LRU_MaxBytes(t *testing.T) {
+	storage := NewWithOptions(Options{TTL: time.Second * 100, MaxBytes: 100})
+	defer storage.Close()
+
+	storage.Set("a.ru", strings.Repeat("a", 40))
+	storage.Set("b.ru", strings.Repeat("b", 40))
+	storage.Set("c.ru", strings.Repeat("c", 40))
+
+	if _, found := storage.Get("a.ru"); found {
+		t.Errorf("least recently used entry a.ru not evicted")
+	}
+
+	if stats := storage.Stats(); stats.Bytes > 100 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	storage.Set("huge.ru", strings.Repeat("h", 1000))
+	if _, found := storage.Get("huge.ru"); found {
+		t.Errorf("entry bigger than MaxBytes stored")
+	}
+}
+
+func TestWhoisDataStorage_Sweep(t *testing.T) {
+	storage := NewWithOptions(Options{TTL: time.Second * 100})
+	defer storage.Close()
+
+	for i := 0; i < 100; i++ {
+		storage.SetWithTTL(fmt.Sprintf("test%d.domain.ru", i), "whois info", time.Millisecond)
+	}
+	storage.Set("alive.domain.ru", "whois info")
+
+	time.Sleep(time.Millisecond * 10)
+	storage.sweep()
+
+	if stats := storage.Stats(); stats.Entries != 1 || stats.Expired != 100 {
+		t.Errorf("unexpected stats after sweep: %+v", stats)
+	}
+}
+
+func TestWhoisDataStorage_GetEntryAndStats(t *testing.T) {
+	storage := New(time.Second*100, time.Hour)
+	defer storage.Close()
+
+	fetched := time.Now().Add(-time.Second * 30)
+	_ = storage.SetEntry("test.domain.ru", Entry{Raw: "whois info", FetchedAt: fetched, TTL: time.Minute,
+		Upstream: "whois.tcinet.ru:43"})
+	_ = storage.SetEntry("expired.domain.ru", Entry{Raw: "whois info", FetchedAt: fetched, TTL: time.Second})
+
+	entry, found, err := storage.GetEntry("test.domain.ru")
+	if err != nil || !found {
+		t.Fatalf("not found entry: %v", err)
+	}
+
+	if entry.Upstream != "whois.tcinet.ru:43" || !entry.FetchedAt.Equal(fetched) ||
+		entry.Age() < time.Second*30 || entry.Remaining() > time.Second*30 || entry.Remaining() < time.Second*25 {
+		t.Errorf("unexpected entry metadata: %+v age: %s remaining: %s", entry, entry.Age(), entry.Remaining())
+	}
+
+	storage.Get("expired.domain.ru")
+	storage.Get("unknown.domain.ru")
+
+	stats := storage.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Expired != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestWhoisDataStorage_HotEntries(t *testing.T) {
+	storage := New(time.Second*100, time.Hour)
+	defer storage.Close()
+
+	_ = storage.SetEntry("hot.domain.ru", Entry{Raw: "whois", TTL: time.Second * 5})
+	_ = storage.SetEntry("hotter.domain.ru", Entry{Raw: "whois", TTL: time.Second * 5})
+	_ = storage.SetEntry("cold.domain.ru", Entry{Raw: "whois", TTL: time.Second * 5})
+	_ = storage.SetEntry("fresh.domain.ru", Entry{Raw: "whois", TTL: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		storage.Get("hot.domain.ru")
+		storage.Get("fresh.domain.ru")
+	}
+	for i := 0; i < 5; i++ {
+		storage.Get("hotter.domain.ru")
+	}
+	storage.Get("cold.domain.ru")
+
+	hot := storage.HotEntries(2, time.Second*10, 0)
+	if len(hot) != 2 || hot[0] != "hotter.domain.ru" || hot[1] != "hot.domain.ru" {
+		t.Errorf("unexpected hot entries: %v", hot)
+	}
+
+	if hot := storage.HotEntries(2, time.Second*10, 1); len(hot) != 1 || hot[0] != "hotter.domain.ru" {
+		t.Errorf("unexpected limited hot entries: %v", hot)
+	}
+
+	// перезапись (refresh) сбрасывает счетчик обращений
+	storage.Set("hotter.domain.ru", "new whois")
+	if hot := storage.HotEntries(2, time.Hour*2, 0); len(hot) != 2 || hot[0] == "hotter.domain.ru" || hot[1] == "hotter.domain.ru" {
+		t.Errorf("unexpected hot entries after refresh: %v", hot)
+	}
+}
@@ -0,0 +1,31 @@
+package upstream
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Port - порт whois (RFC 3912)
+const Port = "43"
+
+// WithPort - адрес whois сервера "host" или "host:port" с портом, по умолчанию Port. Пустой адрес не меняется.
+func WithPort(server string) string {
+	if server == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, Port)
+}
+
+// NormalizeZone - зона в виде ключа таблицы whois серверов: без точек по краям, lower case, punycode
+func NormalizeZone(zone string) string {
+	zone = strings.ToLower(strings.Trim(zone, "."))
+	if ascii, err := idna.Lookup.ToASCII(zone); err == nil {
+		zone = ascii
+	}
+	return zone
+}
@@ -0,0 +1,32 @@
+package upstream
+
+import "testing"
+
+func TestWithPort(t *testing.T) {
+	testCases := map[string]string{
+		"":                     "",
+		"whois.tcinet.ru":      "whois.tcinet.ru:43",
+		"whois.tcinet.ru:4343": "whois.tcinet.ru:4343",
+		"::1":                  "[::1]:43",
+		"[::1]:4343":           "[::1]:4343",
+	}
+	for server, expected := range testCases {
+		if addr := WithPort(server); addr != expected {
+			t.Errorf("unexpected address for %q: %s <> %s", server, addr, expected)
+		}
+	}
+}
+
+func TestNormalizeZone(t *testing.T) {
+	testCases := map[string]string{
+		"ru":      "ru",
+		".CO.UK.": "co.uk",
+		"рф":      "xn--p1ai",
+		"РФ":      "xn--p1ai",
+	}
+	for zone, expected := range testCases {
+		if normalized := NormalizeZone(zone); normalized != expected {
+			t.Errorf("unexpected zone for %q: %s <> %s", zone, normalized, expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package upstream
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorClass - класс ошибки обработки запроса, определяет шаблон ответа клиенту и поле error_class в логе
+type ErrorClass string
+
+const (
+	ErrorInvalidQuery    ErrorClass = "invalidQuery"
+	ErrorUnsupportedTLD  ErrorClass = "unsupportedTLD"
+	ErrorUpstreamTimeout ErrorClass = "upstreamTimeout"
+	ErrorUpstreamRefused ErrorClass = "upstreamRefused"
+	ErrorRateLimited     ErrorClass = "rateLimited"
+	ErrorInternal        ErrorClass = "internal"
+)
+
+// первопричины ошибок с классом (errors.WithMessage поверх них), остальное - по типу ошибки
+var (
+	ErrInvalidQuery    = errors.New("invalid query")
+	ErrUnsupportedTLD  = errors.New("unsupported tld")
+	ErrUpstreamRefused = errors.New("upstream refused")
+	ErrRateLimited     = errors.New("rate limited")
+)
+
+// ClassifyError - класс ошибки по первопричине
+func ClassifyError(err error) ErrorClass {
+	cause := errors.Cause(err)
+	switch cause {
+	case ErrInvalidQuery:
+		return ErrorInvalidQuery
+	case ErrUnsupportedTLD:
+		return ErrorUnsupportedTLD
+	case ErrUpstreamRefused:
+		return ErrorUpstreamRefused
+	case ErrRateLimited:
+		return ErrorRateLimited
+	}
+
+	if netErr, ok := cause.(net.Error); ok && netErr.Timeout() {
+		return ErrorUpstreamTimeout
+	}
+	if _, ok := cause.(*net.OpError); ok { // connection refused, reset, no route и т.д.
+		return ErrorUpstreamRefused
+	}
+
+	return ErrorInternal
+}
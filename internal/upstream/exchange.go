@@ -0,0 +1,157 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const ResponseSizeDefault = 1 << 20 // байт, максимальный размер ответа whois сервера
+
+// Timeouts - таймауты запроса к whois серверу, 0 - без ограничения
+type Timeouts struct {
+	Dial      time.Duration // установка соединения
+	Write     time.Duration // отправка запроса
+	FirstByte time.Duration // от отправки запроса до первого байта ответа
+	Idle      time.Duration // между частями ответа
+	Total     time.Duration // весь запрос: соединение, отправка и чтение ответа
+}
+
+// DialFunc - установка соединения с whois сервером (net.Dialer.DialContext, прокси и т.д.)
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DefaultTimeouts - таймауты whois клиентов
+var DefaultTimeouts = Timeouts{
+	Dial:      10 * time.Second,
+	Write:     time.Second,
+	FirstByte: 5 * time.Second,
+	Idle:      5 * time.Second,
+	Total:     30 * time.Second,
+}
+
+// Exchange - запрос query к whois серверу addr и ответ до EOF (см. readAnswer) с таймаутами t.
+// dial - установка соединения, nil - net.Dialer. Истечение срока или отмена ctx прерывает запрос, ошибка - ctx.Err().
+func Exchange(ctx context.Context, dial DialFunc, addr, query string, t Timeouts, dst io.Writer, maxSize int) (result string, err error) {
+	if t.Total > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Total)
+		defer cancel()
+	}
+
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dialContext(ctx, dial, addr, t.Dial)
+	if err != nil {
+		return "", interrupted(ctx, errors.WithMessagef(err, "can't connect to: %s", addr))
+	}
+
+	// закрытие соединения прерывает чтение и запись, выставленные ими deadline не мешают
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		close(done)
+		if e := conn.Close(); err == nil && ctx.Err() == nil {
+			err = e
+		}
+		err = interrupted(ctx, err)
+	}()
+
+	if err = writeQuery(conn, t.Write, query); err != nil {
+		return "", err
+	}
+
+	return readAnswer(conn, dst, maxSize, t.FirstByte, t.Idle)
+}
+
+func dialContext(ctx context.Context, dial DialFunc, addr string, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return dial(ctx, "tcp", addr)
+}
+
+// interrupted - ошибка запроса, прерванного по ctx, заменяется на ctx.Err()
+func interrupted(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	return errors.WithMessage(ctx.Err(), "whois request interrupted")
+}
+
+// writeQuery - запрос к whois серверу: query и "\r\n" (RFC 3912)
+func writeQuery(conn net.Conn, timeout time.Duration, query string) error {
+	if err := conn.SetWriteDeadline(deadline(timeout)); err != nil {
+		return errors.WithMessage(err, "error while SetWriteDeadline()")
+	}
+	_, err := fmt.Fprintf(conn, "%s\r\n", query)
+	return errors.WithMessage(err, "error while write whois query")
+}
+
+// readAnswer - ответ whois сервера до EOF (RFC 3912: сервер закрывает соединение после ответа),
+// прочитанное сразу пишется в dst (если задан). Ответ больше maxSize обрезается со строкой "% response truncated".
+// firstByte - ожидание начала ответа, idle - ожидание каждой следующей части, 0 - без ограничения.
+func readAnswer(conn net.Conn, dst io.Writer, maxSize int, firstByte, idle time.Duration) (string, error) {
+	var buf bytes.Buffer
+	tmp := make([]byte, 4096)
+	for {
+		timeout := idle
+		if buf.Len() == 0 {
+			timeout = firstByte
+		}
+		if err := conn.SetReadDeadline(deadline(timeout)); err != nil {
+			return "", errors.WithMessage(err, "error while SetReadDeadline()")
+		}
+
+		n, err := conn.Read(tmp)
+		if n > 0 {
+			truncated := buf.Len()+n > maxSize
+			if truncated {
+				n = maxSize - buf.Len()
+			}
+
+			buf.Write(tmp[:n])
+			if dst != nil {
+				_, _ = dst.Write(tmp[:n])
+			}
+
+			if truncated {
+				marker := fmt.Sprintf("\r\n%% response truncated: more than %d bytes\r\n", maxSize)
+				buf.WriteString(marker)
+				if dst != nil {
+					_, _ = io.WriteString(dst, marker)
+				}
+				return buf.String(), nil
+			}
+		}
+
+		if err == io.EOF {
+			return buf.String(), nil
+		}
+		if err != nil {
+			return "", errors.WithMessage(err, "error while read whois answer")
+		}
+	}
+}
+
+// deadline - срок через timeout, 0 - без срока
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
@@ -0,0 +1,121 @@
+package upstream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chunkedWhois - whois сервер, отдающий ответ частями: следующая часть после сигнала next
+type chunkedWhois struct {
+	l    net.Listener
+	next chan struct{}
+}
+
+func newChunkedWhois(t *testing.T, chunks []string) *chunkedWhois {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+
+	c := &chunkedWhois{l: l, next: make(chan struct{}, len(chunks))}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+			return
+		}
+		for i, chunk := range chunks {
+			if i > 0 {
+				<-c.next
+			}
+			_, _ = io.WriteString(conn, chunk)
+		}
+	}()
+
+	return c
+}
+
+func TestReadAnswer(t *testing.T) {
+	long := strings.Repeat("remarks:       line of a long registry answer\r\n", 200)
+
+	testCases := []struct {
+		answer   string
+		maxSize  int
+		expected string
+	}{
+		{long, ResponseSizeDefault, long},
+		{"short\r\n", ResponseSizeDefault, "short\r\n"},
+		{long, 100, long[:100] + "\r\n% response truncated: more than 100 bytes\r\n"},
+	}
+
+	for n, test := range testCases {
+		server, clientConn := net.Pipe()
+		go func() {
+			// куски, заканчивающиеся "\r\n", не должны прерывать чтение
+			for i := 0; i < len(test.answer); i += 47 {
+				end := i + 47
+				if end > len(test.answer) {
+					end = len(test.answer)
+				}
+				_, _ = io.WriteString(server, test.answer[i:end])
+			}
+			_ = server.Close()
+		}()
+
+		var dst bytes.Buffer
+		answer, err := readAnswer(clientConn, &dst, test.maxSize, time.Second, time.Second)
+		_ = clientConn.Close()
+		if err != nil || answer != test.expected || dst.String() != test.expected {
+			t.Errorf("unexpected answer for test case #%d: %v (%d bytes, %d bytes streamed)", n, err, len(answer), dst.Len())
+		}
+	}
+
+	server, clientConn := net.Pipe()
+	defer server.Close()
+	go func() { _, _ = io.WriteString(server, "partial") }()
+	if _, err := readAnswer(clientConn, nil, ResponseSizeDefault, 50*time.Millisecond, 50*time.Millisecond); err == nil {
+		t.Error("no error for read timeout")
+	}
+}
+
+func TestExchange_Timeouts(t *testing.T) {
+	short := 100 * time.Millisecond
+
+	testCases := []struct {
+		chunks   []string
+		timeouts Timeouts
+	}{
+		{[]string{"", "late answer\r\n"}, Timeouts{FirstByte: short, Idle: time.Minute}},
+		{[]string{"domain: example.ru\r\n", "late tail\r\n"}, Timeouts{FirstByte: time.Minute, Idle: short}},
+		{[]string{"domain: example.ru\r\n", "late tail\r\n"}, Timeouts{FirstByte: time.Minute, Idle: time.Minute, Total: short}},
+	}
+
+	for n, test := range testCases {
+		server := newChunkedWhois(t, test.chunks)
+
+		started := time.Now()
+		_, err := Exchange(context.Background(), nil, server.l.Addr().String(), "example.ru", test.timeouts, nil, ResponseSizeDefault)
+		if class := ClassifyError(err); class != ErrorUpstreamTimeout || time.Since(started) > time.Second {
+			t.Errorf("unexpected result for test case #%d: %s %v (%s)", n, class, err, time.Since(started))
+		}
+
+		server.next <- struct{}{}
+		_ = server.l.Close()
+	}
+
+	server := newChunkedWhois(t, []string{"domain: example.ru\r\n"})
+	defer server.l.Close()
+	if answer, err := Exchange(context.Background(), nil, server.l.Addr().String(), "example.ru", DefaultTimeouts, nil, ResponseSizeDefault); err != nil || answer != "domain: example.ru\r\n" {
+		t.Errorf("unexpected answer: %q %v", answer, err)
+	}
+}
@@ -0,0 +1,230 @@
+package watch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/parser"
+)
+
+const (
+	EventChanged  = "changed"
+	EventExpiring = "expiring"
+)
+
+// ThresholdsDefault - дней до окончания регистрации
+var ThresholdsDefault = []int{30, 7, 1}
+
+type (
+	// LookupFunc - сырой whois ответ по домену
+	LookupFunc = func(domain string) (string, error)
+
+	// NotifyFunc - доставка события (Webhook.Send)
+	NotifyFunc = func(ev Event) error
+
+	Change struct {
+		Field string `json:"field"`
+		Old   string `json:"old"`
+		New   string `json:"new"`
+	}
+
+	// Event - уведомление об изменении полей домена или приближении окончания регистрации
+	Event struct {
+		Type     string    `json:"type"`
+		Domain   string    `json:"domain"`
+		Changes  []Change  `json:"changes,omitempty"`
+		Expires  time.Time `json:"expires"`
+		DaysLeft int       `json:"daysLeft,omitempty"`
+		Time     time.Time `json:"time"`
+	}
+
+	// Watcher - сравнение разобранных полей whois ответов с предыдущим состоянием.
+	// Состояние меняется только после успешной доставки всех событий по домену,
+	// недоставленные события повторяются при следующей проверке.
+	Watcher struct {
+		domains    []string
+		lookup     LookupFunc
+		notify     NotifyFunc
+		thresholds []int // по убыванию
+		statePath  string
+
+		mu     sync.Mutex
+		states map[string]domainState
+
+		now func() time.Time
+	}
+
+	domainState struct {
+		Record   parser.Record `json:"record"`
+		Notified int           `json:"notified,omitempty"` // наименьший порог (дней), о котором уже уведомили
+	}
+)
+
+// New - statePath ("" - состояние только в памяти) сохраняет состояние между рестартами
+func New(domains []string, lookup LookupFunc, notify NotifyFunc, thresholds []int, statePath string) (*Watcher, error) {
+	if len(thresholds) == 0 {
+		thresholds = ThresholdsDefault
+	}
+	thresholds = append([]int{}, thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+
+	w := &Watcher{
+		domains:    domains,
+		lookup:     lookup,
+		notify:     notify,
+		thresholds: thresholds,
+		statePath:  statePath,
+		states:     map[string]domainState{},
+		now:        time.Now,
+	}
+
+	if statePath != "" {
+		b, err := ioutil.ReadFile(statePath)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, errors.WithMessagef(err, "can't read watch state %s", statePath)
+		default:
+			if err := json.Unmarshal(b, &w.states); err != nil {
+				return nil, errors.WithMessagef(err, "can't decode watch state %s", statePath)
+			}
+		}
+	}
+
+	return w, nil
+}
+
+// Domains - список отслеживаемых доменов
+func (w *Watcher) Domains() []string {
+	return w.domains
+}
+
+// Check - проверить домен и отправить события. Первая проверка домена только запоминает состояние
+// (и проверяет окончание регистрации).
+func (w *Watcher) Check(domain string) ([]Event, error) {
+	raw, err := w.lookup(domain)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't lookup %s", domain)
+	}
+
+	now := w.now()
+	rec := parser.Parse(raw)
+
+	w.mu.Lock()
+	prev, known := w.states[domain]
+	w.mu.Unlock()
+
+	next := domainState{Record: rec, Notified: prev.Notified}
+
+	var events []Event
+	if known {
+		if changes := diff(prev.Record, rec); len(changes) > 0 {
+			events = append(events, Event{Type: EventChanged, Domain: domain, Changes: changes, Time: now})
+		}
+	}
+
+	if !rec.Expires.Equal(prev.Record.Expires) {
+		next.Notified = 0 // продление или новая регистрация
+	}
+	if threshold, daysLeft, ok := w.crossed(rec.Expires, now); ok && (next.Notified == 0 || threshold < next.Notified) {
+		events = append(events, Event{Type: EventExpiring, Domain: domain, Expires: rec.Expires, DaysLeft: daysLeft, Time: now})
+		next.Notified = threshold
+	}
+
+	for _, ev := range events {
+		if err := w.notify(ev); err != nil {
+			return events, errors.WithMessagef(err, "can't notify %s event for %s", ev.Type, domain)
+		}
+	}
+
+	w.mu.Lock()
+	w.states[domain] = next
+	w.mu.Unlock()
+
+	return events, nil
+}
+
+// crossed - наименьший порог, до которого дошло окончание регистрации
+func (w *Watcher) crossed(expires, now time.Time) (int, int, bool) {
+	if expires.IsZero() {
+		return 0, 0, false
+	}
+
+	daysLeft := int(expires.Sub(now) / (24 * time.Hour))
+	threshold, ok := 0, false
+	for _, t := range w.thresholds {
+		if daysLeft < t {
+			threshold, ok = t, true
+		}
+	}
+
+	return threshold, daysLeft, ok
+}
+
+// Save - атомарно сохранить состояние (tmp файл + rename)
+func (w *Watcher) Save() error {
+	if w.statePath == "" {
+		return nil
+	}
+
+	w.mu.Lock()
+	b, err := json.Marshal(w.states)
+	w.mu.Unlock()
+	if err != nil {
+		return errors.WithMessage(err, "can't encode watch state")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(w.statePath), filepath.Base(w.statePath)+".tmp-")
+	if err != nil {
+		return errors.WithMessage(err, "can't create watch state tmp file")
+	}
+
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.statePath)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WithMessagef(err, "can't save watch state %s", w.statePath)
+	}
+
+	return nil
+}
+
+func diff(old, cur parser.Record) []Change {
+	var changes []Change
+
+	add := func(field, o, n string) {
+		if o != n {
+			changes = append(changes, Change{Field: field, Old: o, New: n})
+		}
+	}
+
+	add("registrar", old.Registrar, cur.Registrar)
+	add("nameservers", strings.Join(old.Nameservers, ","), strings.Join(cur.Nameservers, ","))
+	add("status", strings.Join(old.Status, ","), strings.Join(cur.Status, ","))
+	add("expires", formatTime(old.Expires), formatTime(cur.Expires))
+
+	return changes
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver - локальный приемник webhook, первые fail запросов отвечает 503
+type receiver struct {
+	mu     sync.Mutex
+	fail   int
+	calls  int
+	events []Event
+}
+
+func (r *receiver) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	if r.fail > 0 {
+		r.fail--
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var ev Event
+	if err := json.NewDecoder(req.Body).Decode(&ev); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, ev)
+}
+
+func (r *receiver) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func whoisAnswer(registrar, ns, paidTill string) string {
+	return "domain:        EXAMPLE.RU\r\n" +
+		"nserver:       " + ns + ".\r\n" +
+		"state:         REGISTERED, DELEGATED, VERIFIED\r\n" +
+		"registrar:     " + registrar + "\r\n" +
+		"paid-till:     " + paidTill + "\r\n"
+}
+
+func TestWatcher_Check(t *testing.T) {
+	recv := &receiver{}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "whois-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	answer := whoisAnswer("RU-CENTER-RU", "ns1.example.ru", "2027-04-01T21:00:00Z")
+
+	webhook := NewWebhook(srv.URL, time.Second, 2, time.Millisecond)
+	lookup := func(domain string) (string, error) { return answer, nil }
+
+	w, err := New([]string{"example.ru"}, lookup, webhook.Send, []int{7, 30}, statePath)
+	if err != nil {
+		t.Fatalf("watcher not created: %v", err)
+	}
+	w.now = func() time.Time { return now }
+
+	// первая проверка - только состояние
+	if events, err := w.Check("example.ru"); err != nil || len(events) != 0 {
+		t.Fatalf("unexpected first check result: %+v %v", events, err)
+	}
+
+	// смена регистратора и NS, webhook сначала недоступен - доставка с повторами
+	answer = whoisAnswer("REGRU-RU", "ns2.example.ru", "2027-04-01T21:00:00Z")
+	recv.fail = 2
+	if _, err := w.Check("example.ru"); err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+
+	events := recv.take()
+	if len(events) != 1 || events[0].Type != EventChanged || len(events[0].Changes) != 2 ||
+		events[0].Changes[0] != (Change{Field: "registrar", Old: "RU-CENTER-RU", New: "REGRU-RU"}) {
+		t.Fatalf("unexpected change events: %+v", events)
+	}
+	if recv.calls != 3 {
+		t.Errorf("unexpected webhook calls: %d", recv.calls)
+	}
+
+	if err := w.Save(); err != nil {
+		t.Fatalf("can't save state: %v", err)
+	}
+
+	// после рестарта без изменений событий нет, приближение окончания регистрации - по каждому порогу один раз
+	w, err = New([]string{"example.ru"}, lookup, webhook.Send, []int{7, 30}, statePath)
+	if err != nil {
+		t.Fatalf("watcher not created: %v", err)
+	}
+
+	for _, step := range []struct {
+		now      time.Time
+		daysLeft int
+	}{
+		{time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{time.Date(2027, 3, 10, 0, 0, 0, 0, time.UTC), 22},
+		{time.Date(2027, 3, 11, 0, 0, 0, 0, time.UTC), 0},
+		{time.Date(2027, 3, 28, 0, 0, 0, 0, time.UTC), 4},
+		{time.Date(2027, 3, 29, 0, 0, 0, 0, time.UTC), 0},
+	} {
+		stepNow := step.now
+		w.now = func() time.Time { return stepNow }
+		if _, err := w.Check("example.ru"); err != nil {
+			t.Fatalf("check error: %v", err)
+		}
+
+		events := recv.take()
+		switch {
+		case step.daysLeft == 0 && len(events) != 0:
+			t.Errorf("unexpected events at %s: %+v", step.now, events)
+		case step.daysLeft != 0 && (len(events) != 1 || events[0].Type != EventExpiring || events[0].DaysLeft != step.daysLeft):
+			t.Errorf("unexpected expiry events at %s: %+v", step.now, events)
+		}
+	}
+
+	// продление - событие изменения, пороги сбрасываются
+	answer = whoisAnswer("REGRU-RU", "ns2.example.ru", "2028-04-01T21:00:00Z")
+	if events, _ := w.Check("example.ru"); len(events) != 1 || events[0].Changes[0].Field != "expires" {
+		t.Errorf("unexpected renewal events: %+v", events)
+	}
+}
+
+func TestWebhook_Send_Negative(t *testing.T) {
+	recv := &receiver{fail: 10}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	if err := NewWebhook(srv.URL, time.Second, 2, time.Millisecond).Send(Event{Type: EventChanged}); err == nil {
+		t.Errorf("no error for failed webhook")
+	}
+	if recv.calls != 3 {
+		t.Errorf("unexpected webhook calls: %d", recv.calls)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+
+	if err := NewWebhook(bad.URL, time.Second, 2, time.Hour).Send(Event{Type: EventChanged}); err == nil {
+		t.Errorf("no error for rejected event")
+	}
+}
@@ -0,0 +1,83 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	WebhookTimeoutDefault = 5 * time.Second
+	WebhookBackoffDefault = time.Second
+)
+
+// Webhook - отправка событий JSON POST запросом с повторами (ошибка сети, 429 и 5xx)
+type Webhook struct {
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration // удваивается с каждым повтором
+}
+
+func NewWebhook(url string, timeout time.Duration, retries int, backoff time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = WebhookTimeoutDefault
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	if backoff <= 0 {
+		backoff = WebhookBackoffDefault
+	}
+
+	return &Webhook{
+		url:     url,
+		client:  &http.Client{Timeout: timeout},
+		retries: retries,
+		backoff: backoff,
+	}
+}
+
+func (h *Webhook) Send(ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return errors.WithMessage(err, "can't encode event")
+	}
+
+	backoff := h.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := h.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= h.retries {
+			return errors.WithMessagef(err, "webhook failed after %d attempts", attempt+1)
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *Webhook) post(body []byte) (bool, error) {
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, errors.Errorf("webhook status %d", resp.StatusCode)
+	default:
+		return false, errors.Errorf("webhook status %d", resp.StatusCode)
+	}
+}
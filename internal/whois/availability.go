@@ -0,0 +1,155 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+type Availability string
+
+const (
+	Available  Availability = "available"
+	Registered Availability = "registered"
+	Unknown    Availability = "unknown"
+
+	AvailabilityTTLDefault = time.Minute
+
+	// availabilityKeyword - запрос по 43 порту: "available example.ru"
+	availabilityKeyword = "available"
+	// availabilityKeyPrefix - результаты проверки кэшируются отдельно от whois ответов
+	availabilityKeyPrefix = "avail:"
+)
+
+// AvailabilityResult - ответ проверки "свободен ли домен"
+type AvailabilityResult struct {
+	Domain string       `json:"domain"`
+	Status Availability `json:"status"`
+	Reason string       `json:"reason"`
+	Cached bool         `json:"cached"`
+}
+
+// availability - по шаблонам реестра: not found -> available, found -> registered.
+// Если у реестра есть found шаблоны и ни один шаблон не сработал - unknown.
+func (c *classifier) availability(whoisHost, whoisInfo string) (Availability, string) {
+	if strings.TrimSpace(whoisInfo) == "" {
+		return Unknown, "empty whois answer"
+	}
+
+	p := c.patterns(whoisHost)
+
+	if re := matchFirst(p.err, whoisInfo); re != nil {
+		return Unknown, "whois server error, matched " + re.String()
+	}
+	if re := matchFirst(p.notFound, whoisInfo); re != nil {
+		return Available, "matched not found pattern " + re.String()
+	}
+	if re := matchFirst(p.found, whoisInfo); re != nil {
+		return Registered, "matched found pattern " + re.String()
+	}
+	if len(p.found) > 0 {
+		return Unknown, "no pattern matched"
+	}
+
+	return Registered, "no not found pattern matched"
+}
+
+func isAvailabilityKey(key string) bool {
+	return strings.HasPrefix(key, availabilityKeyPrefix)
+}
+
+// splitAvailabilityQuery - "available example.ru" -> example.ru
+func splitAvailabilityQuery(query string) (string, bool) {
+	fields := strings.Fields(query)
+	if len(fields) != 2 || !strings.EqualFold(fields[0], availabilityKeyword) {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// CheckAvailability - проверка по ответу whois (кэш, реплика-владелец, whois сервер),
+// результат кэшируется на availability.ttl. Ошибка whois сервера - unknown без кэширования.
+func (w *ProxyWhoisServer) CheckAvailability(ctx context.Context, query string) (AvailabilityResult, error) {
+	fqdn, err := w.queryDomain(query)
+	if err != nil {
+		return AvailabilityResult{}, err
+	}
+	fqdn = w.registrable(fqdn)
+
+	res := AvailabilityResult{Domain: fqdn}
+	key := availabilityKeyPrefix + fqdn
+
+	if entry, found, err := w.cache.GetEntry(key); err != nil {
+		w.logger.WithError(err).Warn("cache get problem")
+	} else if found {
+		res.Status, res.Reason, res.Cached = Availability(entry.Class), entry.Raw, true
+		return res, nil
+	}
+
+	host, port, err := w.getWhoisServer(ctx, fqdn)
+	if err != nil {
+		return res, errors.WithMessagef(err, "error while getWhoisServer()")
+	}
+
+	entry, _, err := w.getWhoisInfoCached(ctx, fqdn, host, port, nil)
+	if err != nil {
+		res.Status, res.Reason = Unknown, "whois server error: "+errors.Cause(err).Error()
+		return res, nil
+	}
+
+	res.Status, res.Reason = w.classifier.availability(host, entry.Raw)
+	if res.Status == Unknown {
+		return res, nil
+	}
+
+	ttl := durationOrDefault(w.cfg.Availability.TTL, AvailabilityTTLDefault)
+	err = w.cache.SetEntry(key, storage.Entry{
+		Raw:       res.Reason,
+		FetchedAt: time.Now(),
+		TTL:       ttl,
+		Upstream:  net.JoinHostPort(host, port),
+		Class:     string(res.Status),
+	})
+	if err != nil {
+		w.logger.WithError(err).Warn("cache set problem")
+	}
+
+	return res, nil
+}
+
+// availabilityAnswer - ответ на "available <domain>" в формате whois
+func (w *ProxyWhoisServer) availabilityAnswer(ctx context.Context, domain string) (string, error) {
+	res, err := w.CheckAvailability(ctx, domain)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("domain:        %s\r\navailability:  %s\r\nreason:        %s\r\n", res.Domain, res.Status, res.Reason), nil
+}
+
+// GET /available?domain=example.ru
+func (w *ProxyWhoisServer) handleAvailable(rw http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		http.Error(rw, "domain is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := w.requestContext(client{ctx: r.Context()})
+	defer cancel()
+
+	res, err := w.CheckAvailability(ctx, domain)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, res)
+}
@@ -0,0 +1,122 @@
+package whois
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func TestClassifier_Availability(t *testing.T) {
+	c, err := newClassifier(nil)
+	if err != nil {
+		t.Fatalf("classifier not created: %v", err)
+	}
+
+	testCases := []struct {
+		host     string
+		answer   string
+		expected Availability
+	}{
+		{"whois.tcinet.ru", "No entries found for the selected source(s).\n", Available},
+		{"whois.tcinet.ru", "domain:        EXAMPLE.RU\nstate:         REGISTERED\n", Registered},
+		{"whois.tcinet.ru", "You have exceeded allowed connection rate.\n", Unknown},
+		{"whois.tcinet.ru", "Some unexpected answer\n", Unknown},
+		{"whois.verisign-grs.com", "No match for \"FREE-DOMAIN.COM\".\n", Available},
+		{"whois.verisign-grs.com", "   Domain Name: EXAMPLE.COM\n", Registered},
+		{"whois.unknown.net", "Domain not found.\n", Available},
+		{"whois.unknown.net", "domain: example.net\n", Registered},
+		{"whois.unknown.net", "", Unknown},
+	}
+
+	for n, test := range testCases {
+		status, reason := c.availability(test.host, test.answer)
+		if status != test.expected || reason == "" {
+			t.Errorf("unexpected result for test case #%d: %s (%s)", n, status, reason)
+		}
+	}
+}
+
+func TestWhoisProxyServer_CheckAvailability(t *testing.T) {
+	upstream := newFakeWhois(t, func(query string) string {
+		if strings.HasPrefix(query, "free") {
+			return "No entries found for the selected source(s).\r\n"
+		}
+		if strings.HasPrefix(query, "limited") {
+			return "Query limit exceeded\r\n"
+		}
+		return "domain:        " + query + "\r\n"
+	})
+	defer upstream.Close()
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.ErrorMsgTemplate = "Bad request params: %s"
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+		cfg.ResultPatterns = map[string]config.ResultPatterns{
+			"127.0.0.1": {NotFound: []string{`(?m)^No entries found`}, Found: []string{`(?m)^domain:\s+\S`}, Error: []string{`(?mi)limit exceeded`}},
+		}
+	})
+	defer w.cache.Close()
+
+	answer, err := w.processRequest(client{}, "available free.ru\r\n")
+	if err != nil || !strings.Contains(answer, "availability:  available\r\n") {
+		t.Errorf("unexpected availability answer: %q %v", answer, err)
+	}
+
+	res, err := w.CheckAvailability(context.Background(), "FREE.ru")
+	if err != nil || res.Status != Available || !res.Cached {
+		t.Errorf("unexpected cached availability: %+v %v", res, err)
+	}
+	if queries := upstream.Queries(); len(queries) != 1 {
+		t.Errorf("unexpected upstream queries: %v", queries)
+	}
+
+	// результат проверки не подменяет whois ответ в кэше
+	if answer, _ := w.processRequest(client{}, "free.ru\r\n"); answer != "No entries found for the selected source(s).\r\n" {
+		t.Errorf("unexpected whois answer: %q", answer)
+	}
+
+	// ответ из кэша whois без повторного запроса к whois серверу
+	_, _ = w.processRequest(client{}, "cached.ru\r\n")
+	if res, err := w.CheckAvailability(context.Background(), "cached.ru"); err != nil || res.Status != Registered || res.Cached {
+		t.Errorf("unexpected availability of whois cached domain: %+v %v", res, err)
+	}
+	if queries := upstream.Queries(); len(queries) != 2 {
+		t.Errorf("unexpected upstream queries: %v", queries)
+	}
+
+	// ответ-ошибка whois сервера - unknown, не кэшируется
+	for i := 0; i < 2; i++ {
+		if res, err := w.CheckAvailability(context.Background(), "limited.ru"); err != nil || res.Status != Unknown || res.Cached {
+			t.Errorf("unexpected availability for error answer: %+v %v", res, err)
+		}
+	}
+
+	if answer, _ := w.processRequest(client{}, "available bad_domain.ru\r\n"); answer != "Bad request params: bad_domain.ru" {
+		t.Errorf("unexpected answer for invalid domain: %q", answer)
+	}
+
+	srv := httptest.NewServer(w.httpHandler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/available?domain=example.ru")
+	if err != nil {
+		t.Fatalf("http request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	res = AvailabilityResult{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || res.Status != Registered || res.Domain != "example.ru" || res.Cached {
+		t.Errorf("unexpected http availability: %+v %v", res, err)
+	}
+
+	// недоступный whois сервер - unknown
+	w.cfg.DomainZoneWhois["su"] = "127.0.0.1:1"
+	if res, err := w.CheckAvailability(context.Background(), "example.su"); err != nil || res.Status != Unknown {
+		t.Errorf("unexpected availability for unreachable whois: %+v %v", res, err)
+	}
+}
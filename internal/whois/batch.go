@@ -0,0 +1,313 @@
+package whois
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+const (
+	BatchConcurrencyDefault = 8
+	BatchMaxItemsDefault    = 10000
+
+	batchLineMax = 256 // байт на строку запроса в HTTP batch
+
+	// batchKeyword - первая строка потокового batch по TCP: дальше запросы по строке до "end" или EOF
+	batchKeyword    = "batch"
+	batchEndKeyword = "end"
+
+	batchStatusOK      = "ok"
+	batchStatusInvalid = "invalid"
+	batchStatusError   = "error"
+)
+
+type batchResult struct {
+	Index  int    `json:"index"`
+	Query  string `json:"query"`
+	Status string `json:"status"` // ok / invalid / error
+	Answer string `json:"answer,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Class  string `json:"class,omitempty"` // класс ошибки, см. ErrorClass
+}
+
+func (w *ProxyWhoisServer) batchConcurrency() int {
+	if w.cfg.Batch.Concurrency > 0 {
+		return w.cfg.Batch.Concurrency
+	}
+	return BatchConcurrencyDefault
+}
+
+func (w *ProxyWhoisServer) batchMaxItems() int {
+	if w.cfg.Batch.MaxItems > 0 {
+		return w.cfg.Batch.MaxItems
+	}
+	return BatchMaxItemsDefault
+}
+
+func (w *ProxyWhoisServer) batchItem(cl client, index int, query string) batchResult {
+	answer, err := w.answerQuery(cl, query)
+
+	res := batchResult{Index: index, Query: query, Status: batchStatusOK, Answer: answer}
+	if err == nil {
+		return res
+	}
+
+	class := upstream.ClassifyError(err)
+	res.Status, res.Error, res.Class = batchStatusError, err.Error(), string(class)
+	if class == upstream.ErrorInvalidQuery || class == upstream.ErrorUnsupportedTLD {
+		res.Status = batchStatusInvalid
+	}
+
+	return res
+}
+
+// runBatch - запросы от next обрабатываются не более batchConcurrency одновременно.
+// emit вызывается из текущей горутины: в порядке запросов (ordered) или по мере готовности.
+// После ошибки emit новые запросы не берутся, уже начатые дорабатывают.
+func (w *ProxyWhoisServer) runBatch(cl client, next func() (string, bool), ordered bool, emit func(batchResult) error) error {
+	concurrency := w.batchConcurrency()
+	slots := make(chan struct{}, concurrency)
+	futures := make(chan chan batchResult, concurrency) // ordered
+	finished := make(chan batchResult, concurrency)     // !ordered
+	stop := make(chan struct{})
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(futures)
+			close(finished)
+		}()
+
+		for index := 0; ; index++ {
+			select {
+			case <-stop:
+				return
+			case <-w.stop:
+				return
+			case slots <- struct{}{}:
+			}
+
+			query, ok := next()
+			if !ok {
+				<-slots
+				return
+			}
+
+			future := make(chan batchResult, 1)
+			if ordered {
+				futures <- future
+			}
+
+			wg.Add(1)
+			go func(index int, query string) {
+				defer wg.Done()
+				res := w.batchItem(cl, index, query)
+				<-slots
+				if ordered {
+					future <- res
+				} else {
+					finished <- res
+				}
+			}(index, query)
+		}
+	}()
+
+	var emitErr error
+	handle := func(res batchResult) {
+		if emitErr != nil {
+			return
+		}
+		if emitErr = emit(res); emitErr != nil {
+			close(stop)
+		}
+	}
+
+	if ordered {
+		for future := range futures {
+			handle(<-future)
+		}
+	} else {
+		for res := range finished {
+			handle(res)
+		}
+	}
+
+	return emitErr
+}
+
+// requestLines - непустые строки запроса
+func requestLines(request string) []string {
+	var lines []string
+	for _, line := range strings.Split(request, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// isBatchRequest - batch только по явной первой строке batchKeyword: без нее запрос однострочный (RFC 3912),
+// остальные строки могли еще не дойти до сервера
+func isBatchRequest(lines []string) bool {
+	return len(lines) > 0 && strings.EqualFold(lines[0], batchKeyword)
+}
+
+// tcpBatch - несколько запросов в одном соединении, ответы в порядке запросов.
+// lines - уже прочитанные строки запроса (с batchKeyword), следующие читаются из conn:
+//
+//	% query 1: example.ru (ok)
+//	<ответ>
+//	% query 2: bad_domain (invalid)
+//	% Error: invalid query "bad_domain"
+//	% batch end: 2 queries, 1 failed
+func (w *ProxyWhoisServer) tcpBatch(conn net.Conn, cl client, lines []string) error {
+	lines = lines[1:]
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 256), w.cfg.MaxLenBuffer+2)
+
+	readTimeout := time.Duration(w.cfg.ReadTimeout) * time.Second
+	writeTimeout := time.Duration(w.cfg.WriteTimeout) * time.Second
+	maxItems := w.batchMaxItems()
+
+	taken, limited := 0, false
+	next := func() (string, bool) {
+		for {
+			var query string
+			switch {
+			case len(lines) > 0:
+				query, lines = lines[0], lines[1:]
+			default:
+				_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+				if !scanner.Scan() {
+					return "", false
+				}
+				query = strings.TrimSpace(scanner.Text())
+			}
+
+			if strings.EqualFold(query, batchEndKeyword) {
+				return "", false
+			}
+			if query == "" {
+				continue
+			}
+			if taken >= maxItems {
+				limited = true
+				return "", false
+			}
+			taken++
+			return query, true
+		}
+	}
+
+	total, failed := 0, 0
+	err := w.runBatch(cl, next, true, func(res batchResult) error {
+		total++
+		if res.Status != batchStatusOK {
+			failed++
+		}
+
+		return writeToConnection(conn, writeTimeout,
+			fmt.Sprintf("%% query %d: %s (%s)\r\n%s", res.Index+1, res.Query, res.Status, strings.TrimRight(res.Answer, "\r\n")))
+	})
+	if err != nil {
+		return err
+	}
+
+	if limited {
+		if err := writeToConnection(conn, writeTimeout, fmt.Sprintf("%% batch limit reached: %d queries", maxItems)); err != nil {
+			return err
+		}
+	}
+
+	return writeToConnection(conn, writeTimeout, fmt.Sprintf("%% batch end: %d queries, %d failed", total, failed))
+}
+
+// POST /batch - запросы JSON {"queries": [...]} или по строке на запрос,
+// результаты в NDJSON (application/x-ndjson) по мере готовности
+func (w *ProxyWhoisServer) handleBatch(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxItems := w.batchMaxItems()
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, int64(maxItems)*batchLineMax+1024))
+	if err != nil {
+		http.Error(rw, "request is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var queries []string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req struct {
+			Queries []string `json:"queries"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(rw, "bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		queries = req.Queries
+	} else {
+		queries = requestLines(string(body))
+	}
+
+	switch {
+	case len(queries) == 0:
+		http.Error(rw, "queries are required", http.StatusBadRequest)
+		return
+	case len(queries) > maxItems:
+		http.Error(rw, fmt.Sprintf("too many queries, max %d", maxItems), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	rw.WriteHeader(http.StatusOK)
+	flusher, _ := rw.(http.Flusher)
+	enc := json.NewEncoder(rw)
+
+	i := 0
+	next := func() (string, bool) {
+		if i >= len(queries) || r.Context().Err() != nil {
+			return "", false
+		}
+		i++
+		return strings.TrimSpace(queries[i-1]), true
+	}
+
+	err = w.runBatch(httpRequestClient(r), next, false, func(res batchResult) error {
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		w.logger.WithError(err).Warn("batch response interrupted")
+	}
+}
+
+// httpRequestClient - параметры клиента HTTP запроса для пост-обработки ответа
+func httpRequestClient(r *http.Request) client {
+	cl := client{ctx: r.Context(), requestID: r.Header.Get("X-Request-ID")}
+	if cl.requestID == "" {
+		cl.requestID = newRequestID()
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		cl.listener = addr
+	}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		cl.remote = addr
+	}
+	return cl
+}
@@ -0,0 +1,163 @@
+package whois
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func newBatchTestServer(t *testing.T, port string) (*ProxyWhoisServer, *fakeWhois) {
+	upstream := newFakeWhois(t, func(query string) string {
+		return "domain:        " + query + "\r\n"
+	})
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.Port = port
+		cfg.ErrorMsgTemplate = "Bad request params: %s"
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+		cfg.Batch = config.Batch{Concurrency: 2, MaxItems: 3}
+	})
+
+	return w, upstream
+}
+
+func TestWhoisProxyServer_TCPBatch(t *testing.T) {
+	w, upstream := newBatchTestServer(t, "50140")
+	defer upstream.Close()
+	if err := w.Start(); err != nil {
+		t.Fatalf("error while server.Start. err: %v", err)
+	}
+	waitServing(t, w.server.Addr())
+	defer w.Stop()
+
+	testCases := []struct {
+		writes     []string
+		closeWrite bool
+		expected   string
+	}{
+		{
+			// несколько запросов одним пакетом до закрытия записи
+			writes:     []string{"batch\r\na.ru\r\nbad_domain.ru\r\nb.ru\r\n"},
+			closeWrite: true,
+			expected: "% query 1: a.ru (ok)\r\ndomain:        a.ru\r\n" +
+				"% query 2: bad_domain.ru (invalid)\r\nBad request params: bad_domain.ru\r\n" +
+				"% query 3: b.ru (ok)\r\ndomain:        b.ru\r\n" +
+				"% batch end: 3 queries, 1 failed\r\n",
+		},
+		{
+			// потоковый режим до "end"
+			writes: []string{"batch\r\n", "c.ru\r\n", "\r\n", "d.ru\r\n", "end\r\n"},
+			expected: "% query 1: c.ru (ok)\r\ndomain:        c.ru\r\n" +
+				"% query 2: d.ru (ok)\r\ndomain:        d.ru\r\n" +
+				"% batch end: 2 queries, 0 failed\r\n",
+		},
+		{
+			// запросы по одному на запись
+			writes: []string{"batch\r\n", "a.ru\r\n", "b.ru\r\n", "end\r\n"},
+			expected: "% query 1: a.ru (ok)\r\ndomain:        a.ru\r\n" +
+				"% query 2: b.ru (ok)\r\ndomain:        b.ru\r\n" +
+				"% batch end: 2 queries, 0 failed\r\n",
+		},
+		{
+			// без batch - только первая строка (RFC 3912)
+			writes:   []string{"a.ru\r\n", "b.ru\r\n"},
+			expected: "domain:        a.ru\r\n\r\n",
+		},
+		{
+			// ограничение числа запросов
+			writes: []string{"batch\r\n", "a.ru\r\nb.ru\r\nc.ru\r\nd.ru\r\n"},
+			expected: "% query 1: a.ru (ok)\r\ndomain:        a.ru\r\n" +
+				"% query 2: b.ru (ok)\r\ndomain:        b.ru\r\n" +
+				"% query 3: c.ru (ok)\r\ndomain:        c.ru\r\n" +
+				"% batch limit reached: 3 queries\r\n" +
+				"% batch end: 3 queries, 0 failed\r\n",
+		},
+	}
+
+	for n, test := range testCases {
+		conn, err := net.Dial("tcp", w.server.Addr())
+		if err != nil {
+			t.Fatalf("can't connect: %v", err)
+		}
+
+		for _, s := range test.writes {
+			_, _ = conn.Write([]byte(s))
+			time.Sleep(time.Millisecond * 20)
+		}
+		if test.closeWrite {
+			_ = conn.(*net.TCPConn).CloseWrite()
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second * 3))
+		answer, err := ioutil.ReadAll(conn)
+		_ = conn.Close()
+		if err != nil || string(answer) != test.expected {
+			t.Errorf("unexpected answer for test case #%d: %v\n%q", n, err, answer)
+		}
+	}
+}
+
+func TestWhoisProxyServer_HTTPBatch(t *testing.T) {
+	w, upstream := newBatchTestServer(t, "0")
+	defer upstream.Close()
+	defer w.cache.Close()
+
+	srv := httptest.NewServer(w.httpHandler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/batch", "application/json",
+		strings.NewReader(`{"queries": ["a.ru", "bad_domain.ru", "b.ru"]}`))
+	if err != nil {
+		t.Fatalf("http request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	results := map[string]batchResult{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var res batchResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatalf("bad result line %q: %v", scanner.Text(), err)
+		}
+		results[res.Query] = res
+	}
+
+	if len(results) != 3 ||
+		results["a.ru"].Status != batchStatusOK || results["a.ru"].Answer != "domain:        a.ru\r\n" || results["a.ru"].Index != 0 ||
+		results["bad_domain.ru"].Status != batchStatusInvalid || results["bad_domain.ru"].Error == "" ||
+		results["b.ru"].Status != batchStatusOK || results["b.ru"].Index != 2 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	for _, test := range []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"text/plain", "a.ru\nb.ru\n", http.StatusOK},
+		{"text/plain", "a.ru\nb.ru\nc.ru\nd.ru\n", http.StatusRequestEntityTooLarge},
+		{"text/plain", "\n", http.StatusBadRequest},
+		{"application/json", "{", http.StatusBadRequest},
+	} {
+		resp, err := http.Post(srv.URL+"/batch", test.contentType, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("http request error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("unexpected status for %q: %d", test.body, resp.StatusCode)
+		}
+	}
+}
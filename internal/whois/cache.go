@@ -0,0 +1,50 @@
+package whois
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+	CacheBackendDisk   = "disk"
+)
+
+// newCache - создание хранилища кэша согласно cacheBackend конфигурации
+func newCache(cfg *config.Service) (storage.Cache, error) {
+	ttl := time.Duration(cfg.CacheTTL) * time.Second
+	sweep := time.Duration(cfg.CacheSweep) * time.Second
+
+	switch strings.ToLower(cfg.CacheBackend.Type) {
+	case "", CacheBackendMemory:
+		return storage.NewWithOptions(storage.Options{
+			TTL:           ttl,
+			SweepInterval: sweep,
+			MaxEntries:    cfg.CacheMaxEntries,
+			MaxBytes:      cfg.CacheMaxBytes,
+		}), nil
+
+	case CacheBackendRedis:
+		redis := cfg.CacheBackend.Redis
+		return storage.NewRedis(storage.RedisOptions{
+			Addr:     redis.Addr,
+			Password: redis.Password,
+			DB:       redis.DB,
+			Prefix:   redis.Prefix,
+			Timeout:  time.Duration(redis.Timeout) * time.Millisecond,
+			PoolSize: redis.PoolSize,
+			TTL:      ttl,
+		})
+
+	case CacheBackendDisk:
+		return storage.NewDisk(cfg.CacheBackend.Disk.Path, ttl, sweep)
+	}
+
+	return nil, errors.Errorf("unknown cache backend type: %s", cfg.CacheBackend.Type)
+}
@@ -0,0 +1,31 @@
+package whois
+
+import (
+	"testing"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+func Test_newCache(t *testing.T) {
+	cfg := config.Service{CacheTTL: 300}
+
+	cache, err := newCache(&cfg)
+	if err != nil {
+		t.Fatalf("memory cache not created. err: %v", err)
+	}
+	if _, ok := cache.(*storage.WhoisDataStorage); !ok {
+		t.Errorf("default cache backend is not memory: %T", cache)
+	}
+
+	for _, backend := range []config.CacheBackend{
+		{Type: "unknown"},
+		{Type: CacheBackendRedis},
+		{Type: CacheBackendDisk},
+	} {
+		cfg.CacheBackend = backend
+		if _, err := newCache(&cfg); err == nil {
+			t.Errorf("no error for cache backend: %+v", backend)
+		}
+	}
+}
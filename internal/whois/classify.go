@@ -0,0 +1,183 @@
+package whois
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+type ResultClass string
+
+const (
+	ClassFound    ResultClass = "found"
+	ClassNotFound ResultClass = "notfound"
+	ClassError    ResultClass = "error"
+)
+
+// шаблоны известных реестров, resultPatterns из конфигурации для того же whois сервера заменяют их
+var defaultResultPatterns = map[string]config.ResultPatterns{
+	"whois.tcinet.ru": {
+		NotFound: []string{`(?m)^No entries found`},
+		Found:    []string{`(?m)^domain:\s+\S`},
+		Error:    []string{`(?mi)^You have exceeded allowed connection rate`, `(?mi)query limit exceeded`},
+	},
+	"whois.verisign-grs.com": {
+		NotFound: []string{`(?m)^No match for`},
+		Found:    []string{`(?m)^\s*Domain Name:\s+\S`},
+	},
+}
+
+// общие шаблоны для whois серверов без своих шаблонов
+var genericResultPatterns = config.ResultPatterns{
+	NotFound: []string{
+		`(?mi)^\s*no match`,
+		`(?mi)no entries found`,
+		`(?mi)^\s*not found`,
+		`(?mi)no data found`,
+		`(?mi)domain not found`,
+		`(?mi)^\s*status:\s*(free|available)\s*$`,
+	},
+	Error: []string{
+		`(?mi)limit exceeded`,
+		`(?mi)too many (requests|queries)`,
+		`(?mi)try again later`,
+	},
+}
+
+type (
+	classifier struct {
+		generic    classPatterns
+		registries map[string]classPatterns
+	}
+
+	classPatterns struct {
+		notFound []*regexp.Regexp
+		found    []*regexp.Regexp
+		err      []*regexp.Regexp
+	}
+)
+
+func newClassifier(custom map[string]config.ResultPatterns) (*classifier, error) {
+	c := &classifier{registries: map[string]classPatterns{}}
+
+	var err error
+	if c.generic, err = compileClassPatterns(genericResultPatterns); err != nil {
+		return nil, errors.WithMessage(err, "generic result patterns")
+	}
+
+	for _, patterns := range []map[string]config.ResultPatterns{defaultResultPatterns, custom} {
+		for host, p := range patterns {
+			cp, err := compileClassPatterns(p)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "result patterns of %s", host)
+			}
+			c.registries[strings.ToLower(host)] = cp
+		}
+	}
+
+	return c, nil
+}
+
+func compileClassPatterns(p config.ResultPatterns) (classPatterns, error) {
+	var (
+		cp  classPatterns
+		err error
+	)
+
+	if cp.notFound, err = compileRegexps(p.NotFound); err != nil {
+		return classPatterns{}, err
+	}
+	if cp.found, err = compileRegexps(p.Found); err != nil {
+		return classPatterns{}, err
+	}
+	if cp.err, err = compileRegexps(p.Error); err != nil {
+		return classPatterns{}, err
+	}
+
+	return cp, nil
+}
+
+func compileRegexps(patterns []string) ([]*regexp.Regexp, error) {
+	r := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "bad regexp %q", p)
+		}
+		r = append(r, re)
+	}
+	return r, nil
+}
+
+// classify - класс ответа whois сервера (found, notFound или error)
+func (c *classifier) classify(whoisHost, whoisInfo string) ResultClass {
+	if strings.TrimSpace(whoisInfo) == "" {
+		return ClassError
+	}
+
+	p := c.patterns(whoisHost)
+
+	if matchAny(p.err, whoisInfo) {
+		return ClassError
+	}
+	if matchAny(p.notFound, whoisInfo) {
+		return ClassNotFound
+	}
+	return ClassFound
+}
+
+// patterns - шаблоны реестра или общие шаблоны для неизвестных whois серверов
+func (c *classifier) patterns(whoisHost string) classPatterns {
+	if p, found := c.registries[strings.ToLower(whoisHost)]; found {
+		return p
+	}
+	return c.generic
+}
+
+func matchAny(r []*regexp.Regexp, s string) bool {
+	return matchFirst(r, s) != nil
+}
+
+func matchFirst(r []*regexp.Regexp, s string) *regexp.Regexp {
+	for _, re := range r {
+		if re.MatchString(s) {
+			return re
+		}
+	}
+	return nil
+}
+
+// cacheTTL - TTL записи кэша: TTL зоны -> TTL класса -> cacheTTL
+func (w *ProxyWhoisServer) cacheTTL(fqdn string, class ResultClass) time.Duration {
+	zones := append([]string{fqdn}, w.domainZones(fqdn)...)
+	for _, zone := range zones {
+		if ttl, found := w.cfg.CacheZoneTTL[zone]; found {
+			if sec := classTTL(ttl, class); sec > 0 {
+				return time.Duration(sec) * time.Second
+			}
+			break
+		}
+	}
+
+	if sec := classTTL(w.cfg.CacheClassTTL, class); sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+
+	return time.Duration(w.cfg.CacheTTL) * time.Second
+}
+
+func classTTL(ttl config.ClassTTL, class ResultClass) int {
+	switch class {
+	case ClassFound:
+		return ttl.Found
+	case ClassNotFound:
+		return ttl.NotFound
+	case ClassError:
+		return ttl.Error
+	}
+	return 0
+}
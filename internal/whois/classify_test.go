@@ -0,0 +1,78 @@
+package whois
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+// go test -covermode=count -coverprofile=coverage.cov && go tool cover -html=coverage.cov
+
+func Test_classify(t *testing.T) {
+	testCases := []struct {
+		host      string
+		whoisInfo string
+		class     ResultClass
+	}{
+		{"whois.tcinet.ru", "% TCI Whois Service. Terms of use:\n\ndomain:        EXAMPLE.RU\nsource:        TCI\n", ClassFound},
+		{"whois.tcinet.ru", "% TCI Whois Service. Terms of use:\n\nNo entries found for the selected source(s).\n", ClassNotFound},
+		{"whois.tcinet.ru", "You have exceeded allowed connection rate\n", ClassError},
+		{"whois.verisign-grs.com", "   Domain Name: EXAMPLE.COM\n", ClassFound},
+		{"whois.verisign-grs.com", "No match for \"NOTEXIST-EXAMPLE.COM\".\n", ClassNotFound},
+		{"whois.custom.net", "Domain not found.\n", ClassNotFound},
+		{"whois.custom.net", "Query limit exceeded\n", ClassError},
+		{"whois.custom.net", "status: busy\n", ClassFound},
+		{"whois.custom.org", "NOPE\n", ClassNotFound},
+		{"whois.custom.org", "", ClassError},
+	}
+
+	c, err := newClassifier(map[string]config.ResultPatterns{
+		"whois.custom.org": {NotFound: []string{`^NOPE`}},
+	})
+	if err != nil {
+		t.Fatalf("classifier not created. err: %v", err)
+	}
+
+	for n, test := range testCases {
+		if class := c.classify(test.host, test.whoisInfo); class != test.class {
+			t.Errorf("unxpected class for test case #%d: %s <> %s", n, class, test.class)
+		}
+	}
+}
+
+func Test_newClassifier_Negative(t *testing.T) {
+	_, err := newClassifier(map[string]config.ResultPatterns{"whois.custom.org": {Error: []string{`(`}}})
+	if err == nil {
+		t.Error("no error for bad regexp")
+	}
+}
+
+func Test_cacheTTL(t *testing.T) {
+	testCases := []struct {
+		fqdn  string
+		class ResultClass
+		ttl   time.Duration
+	}{
+		{"example.com", ClassFound, 300 * time.Second},
+		{"example.com", ClassNotFound, 30 * time.Second},
+		{"example.com", ClassError, 300 * time.Second},
+		{"example.ru", ClassFound, 3600 * time.Second},
+		{"sub.example.ru", ClassNotFound, 10 * time.Second},
+		{"example.ru", ClassError, 5 * time.Second},
+		{"example.su", ClassNotFound, 30 * time.Second},
+	}
+
+	server := newTestServer(t, func(cfg *config.Service) {
+		cfg.CacheClassTTL = config.ClassTTL{NotFound: 30}
+		cfg.CacheZoneTTL = map[string]config.ClassTTL{
+			"ru": {Found: 3600, NotFound: 10, Error: 5},
+		}
+	})
+
+	for n, test := range testCases {
+		if ttl := server.cacheTTL(test.fqdn, test.class); ttl != test.ttl {
+			t.Errorf("unxpected ttl for test case #%d: %s <> %s", n, ttl, test.ttl)
+		}
+	}
+}
@@ -1,25 +1,18 @@
 package whois
 
 import (
-	"fmt"
+	"context"
 	"net"
-	"time"
 
-	"github.com/pkg/errors"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
 )
 
+// Client - запрос к whois серверу с таймаутами upstream.DefaultTimeouts
 func Client(host, port, fqdn string) (string, error) {
-	addr := fmt.Sprintf("%s:%s", host, port)
-
-	conn, err := net.Dial("tcp", addr)
-	if err != nil {
-		return "", errors.WithMessagef(err, "can't connect to: %s", addr)
-	}
-
-	err = writeToConnection(conn, time.Millisecond*100, fqdn)
-	if err != nil {
-		return "", errors.WithMessage(err, "can't send to connect")
-	}
+	return ClientContext(context.Background(), host, port, fqdn, upstream.DefaultTimeouts)
+}
 
-	return readFromConnection(conn, 4096, time.Second*5)
+// ClientContext - запрос к whois серверу с таймаутами t, истечение срока или отмена ctx прерывает запрос
+func ClientContext(ctx context.Context, host, port, fqdn string, t upstream.Timeouts) (string, error) {
+	return upstream.Exchange(ctx, nil, net.JoinHostPort(host, port), fqdn, t, nil, upstream.ResponseSizeDefault)
 }
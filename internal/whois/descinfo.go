@@ -0,0 +1,78 @@
+package whois
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/descinfo"
+)
+
+const (
+	DescInfoSourceFile   = "file"
+	DescInfoSourceHTTP   = "http"
+	DescInfoSourceSQLite = "sqlite"
+)
+
+func newDescInfoSource(cfg config.DescInfoSource) (*descinfo.Source, error) {
+	var provider descinfo.Provider
+
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case DescInfoSourceFile:
+		p, err := descinfo.NewFileProvider(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+	case DescInfoSourceHTTP:
+		if cfg.URL == "" {
+			return nil, errors.New("desc info source url is empty")
+		}
+		provider = descinfo.NewHTTPProvider(cfg.URL, cfg.Token, time.Duration(cfg.Timeout)*time.Millisecond)
+	case DescInfoSourceSQLite:
+		if !descinfo.SQLiteSupported {
+			return nil, errors.New("desc info source type sqlite is not supported by this binary, build it with -tags sqlite (cgo)")
+		}
+		if cfg.Path == "" {
+			return nil, errors.New("desc info source path is empty")
+		}
+		provider = descinfo.NewSQLiteProvider(cfg.Path, cfg.Query)
+	default:
+		return nil, errors.Errorf("unknown desc info source type %q", cfg.Type)
+	}
+
+	return descinfo.NewSource(provider, time.Duration(cfg.Interval)*time.Second)
+}
+
+// descInfoLines - строки для домена: addWhoisDescInfo из конфигурации и внешнего источника
+func (w *ProxyWhoisServer) descInfoLines(fqdn string) []string {
+	lines := w.cfg.AddWhoisDescInfo[fqdn]
+	if external, found := w.descInfo.Lookup(fqdn); found {
+		lines = append(append([]string{}, lines...), external...)
+	}
+	return lines
+}
+
+func (w *ProxyWhoisServer) descInfoLoop() {
+	if w.descInfo == nil {
+		return
+	}
+
+	w.descInfo.Run(w.stop, func(stats descinfo.Stats, err error) {
+		if err != nil {
+			w.logger.WithError(err).Error("desc info reload problem")
+			return
+		}
+		w.logger.Infof("desc info reloaded from %s: %d domains, %d lines in %s",
+			stats.Provider, stats.Domains, stats.Lines, stats.Duration)
+	})
+}
+
+// GET /descinfo/stats
+func (w *ProxyWhoisServer) handleDescInfoStats(rw http.ResponseWriter, _ *http.Request) {
+	writeJSON(rw, http.StatusOK, w.descInfo.Stats())
+}
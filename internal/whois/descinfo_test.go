@@ -0,0 +1,78 @@
+package whois
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/descinfo"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+func TestWhoisProxyServer_DescInfoSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-descinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "desc.csv")
+	_ = ioutil.WriteFile(path, []byte("example.ru,descr:         from billing\n"), 0644)
+
+	cfg := newTestConfig(func(cfg *config.Service) {
+		cfg.AddWhoisDescInfo = map[string][]string{"example.ru": {"descr:         from config"}}
+		cfg.DescInfoSource = config.DescInfoSource{Type: DescInfoSourceFile, Path: path}
+	})
+
+	server, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	defer server.Stop()
+
+	entry := storage.Entry{Raw: "domain:        EXAMPLE.RU\nsource:        TCI\n"}
+	response, err := server.postProcess(client{}, "example.ru", entry)
+	expected := "domain:        EXAMPLE.RU\nsource:        TCI\ndescr:         from config\ndescr:         from billing\n"
+	if err != nil || response != expected {
+		t.Errorf("unexpected response: %q %v", response, err)
+	}
+
+	// новые данные источника подменяют старые целиком
+	_ = ioutil.WriteFile(path, []byte("other.ru,descr:         new customer\n"), 0644)
+	if err := server.descInfo.Reload(); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if lines := server.descInfoLines("example.ru"); len(lines) != 1 {
+		t.Errorf("unexpected lines after reload: %v", lines)
+	}
+	if lines := server.descInfoLines("other.ru"); len(lines) != 1 {
+		t.Errorf("unexpected lines after reload: %v", lines)
+	}
+
+	for _, bad := range []config.DescInfoSource{
+		{Type: "ftp"},
+		{Type: DescInfoSourceFile, Path: filepath.Join(dir, "missing.csv")},
+		{Type: DescInfoSourceHTTP},
+	} {
+		cfg.DescInfoSource = bad
+		if _, err := NewWhoisProxyServer(cfg, &logrus.Logger{}); err == nil {
+			t.Errorf("no error for desc info source: %+v", bad)
+		}
+	}
+}
+
+func Test_newDescInfoSource_SQLite(t *testing.T) {
+	if descinfo.SQLiteSupported {
+		t.Skip("built with sqlite driver")
+	}
+
+	_, err := newDescInfoSource(config.DescInfoSource{Type: DescInfoSourceSQLite, Path: "desc.db"})
+	if err == nil || !strings.Contains(err.Error(), "-tags sqlite") {
+		t.Errorf("unexpected error for sqlite source without driver: %v", err)
+	}
+}
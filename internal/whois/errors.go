@@ -0,0 +1,115 @@
+package whois
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+// ответы по умолчанию (RFC 3912: строки-комментарии "%"), плейсхолдеры {query}, {reason} и {request_id}
+var defaultErrorTemplates = map[upstream.ErrorClass]string{
+	upstream.ErrorInvalidQuery:    "% Error: invalid query \"{query}\": {reason}\r\n% Request ID: {request_id}",
+	upstream.ErrorUnsupportedTLD:  "% Error: no whois server for \"{query}\"\r\n% Request ID: {request_id}",
+	upstream.ErrorUpstreamTimeout: "% Error: whois server timed out for \"{query}\", try again later\r\n% Request ID: {request_id}",
+	upstream.ErrorUpstreamRefused: "% Error: whois server is unavailable for \"{query}\", try again later\r\n% Request ID: {request_id}",
+	upstream.ErrorRateLimited:     "% Error: query rate limit exceeded for \"{query}\", try again later\r\n% Request ID: {request_id}",
+	upstream.ErrorInternal:        "% Error: internal error for \"{query}\"\r\n% Request ID: {request_id}",
+}
+
+// newErrorTemplates - шаблоны из конфигурации поверх шаблонов по умолчанию
+func newErrorTemplates(custom map[string]string) (map[upstream.ErrorClass]string, error) {
+	templates := make(map[upstream.ErrorClass]string, len(defaultErrorTemplates))
+	for class, tpl := range defaultErrorTemplates {
+		templates[class] = tpl
+	}
+
+	for name, tpl := range custom {
+		class := upstream.ErrorClass(name)
+		if _, found := defaultErrorTemplates[class]; !found {
+			return nil, errors.Errorf("unknown error class %q", name)
+		}
+		if tpl != "" {
+			templates[class] = tpl
+		}
+	}
+
+	return templates, nil
+}
+
+// upstreamError - ответ whois сервера, который нельзя отдавать как результат запроса
+func upstreamError(class ResultClass, raw string) error {
+	if class != ClassError {
+		return nil
+	}
+	if strings.TrimSpace(raw) == "" {
+		return errors.WithMessage(upstream.ErrUpstreamRefused, "empty answer")
+	}
+	return errors.WithMessage(upstream.ErrRateLimited, "upstream answer matches error patterns")
+}
+
+// errorAnswer - ответ клиенту по шаблону класса ошибки, ошибка логируется с классом и request id
+func (w *ProxyWhoisServer) errorAnswer(cl client, query string, err error) string {
+	class := upstream.ClassifyError(err)
+
+	entry := w.logger.WithError(err).WithFields(logrus.Fields{
+		"error_class": class,
+		"request_id":  cl.requestID,
+		"query":       query,
+	})
+	if class == upstream.ErrorInvalidQuery || class == upstream.ErrorUnsupportedTLD {
+		entry.Warn("query rejected")
+	} else {
+		entry.Error("query failed")
+	}
+
+	// legacy: errorMsgTemplate (fmt, %s - запрос) для неверных запросов, если не задан errorTemplates.invalidQuery
+	if class == upstream.ErrorInvalidQuery && w.cfg.ErrorMsgTemplate != "" && w.cfg.ErrorTemplates[string(upstream.ErrorInvalidQuery)] == "" {
+		if strings.Contains(w.cfg.ErrorMsgTemplate, "%s") {
+			return fmt.Sprintf(w.cfg.ErrorMsgTemplate, query)
+		}
+		return w.cfg.ErrorMsgTemplate
+	}
+
+	return strings.NewReplacer("{query}", query, "{reason}", errorReason(err), "{request_id}", cl.requestID).
+		Replace(w.errorTemplates[class])
+}
+
+// errorReason - текст ошибки без первопричины ("label "-a" starts ... : invalid query" -> "label "-a" starts ...")
+func errorReason(err error) string {
+	msg := err.Error()
+	if cause := errors.Cause(err); cause != err {
+		msg = strings.TrimSuffix(msg, ": "+cause.Error())
+	}
+	return msg
+}
+
+// errorStatus - HTTP статус ответа API для ошибки
+func errorStatus(err error) int {
+	switch upstream.ClassifyError(err) {
+	case upstream.ErrorInvalidQuery, upstream.ErrorUnsupportedTLD:
+		return http.StatusBadRequest
+	case upstream.ErrorUpstreamTimeout:
+		return http.StatusGatewayTimeout
+	case upstream.ErrorUpstreamRefused:
+		return http.StatusBadGateway
+	case upstream.ErrorRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// newRequestID - идентификатор запроса для ответа об ошибке и логов
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "-"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,111 @@
+package whois
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+func TestErrorClass(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, timeoutErr := (&net.Dialer{}).DialContext(ctx, "tcp", "127.0.0.1:1")
+
+	_, refusedErr := net.Dial("tcp", "127.0.0.1:1")
+
+	testCases := []struct {
+		err   error
+		class upstream.ErrorClass
+	}{
+		{errors.WithMessage(upstream.ErrInvalidQuery, "idna"), upstream.ErrorInvalidQuery},
+		{errors.WithMessage(upstream.ErrUnsupportedTLD, "no zone"), upstream.ErrorUnsupportedTLD},
+		{errors.WithMessage(timeoutErr, "error while getWhoisInfo()"), upstream.ErrorUpstreamTimeout},
+		{errors.WithMessage(refusedErr, "error while getWhoisInfo()"), upstream.ErrorUpstreamRefused},
+		{errors.WithMessage(context.DeadlineExceeded, "whois request interrupted"), upstream.ErrorUpstreamTimeout},
+		{upstreamError(ClassError, "You have exceeded allowed connection rate\n"), upstream.ErrorRateLimited},
+		{upstreamError(ClassError, ""), upstream.ErrorUpstreamRefused},
+		{errors.New("something else"), upstream.ErrorInternal},
+	}
+
+	for n, test := range testCases {
+		if class := upstream.ClassifyError(test.err); class != test.class {
+			t.Errorf("unexpected class for test case #%d (%v): %s <> %s", n, test.err, class, test.class)
+		}
+	}
+
+	if err := upstreamError(ClassFound, "domain: example.ru\n"); err != nil {
+		t.Errorf("unexpected error for found answer: %v", err)
+	}
+}
+
+func TestNewErrorTemplates(t *testing.T) {
+	templates, err := newErrorTemplates(map[string]string{"rateLimited": "% Error: slow down ({request_id})"})
+	if err != nil {
+		t.Fatalf("templates not loaded: %v", err)
+	}
+	if templates[upstream.ErrorRateLimited] != "% Error: slow down ({request_id})" || templates[upstream.ErrorInternal] != defaultErrorTemplates[upstream.ErrorInternal] {
+		t.Errorf("unexpected templates: %v", templates)
+	}
+
+	if _, err := newErrorTemplates(map[string]string{"unknown": "x"}); err == nil {
+		t.Error("no error for unknown error class")
+	}
+}
+
+func TestWhoisProxyServer_ErrorAnswer(t *testing.T) {
+	upstream := newFakeWhois(t, func(query string) string {
+		return "You have exceeded allowed connection rate.\r\n"
+	})
+	defer upstream.Close()
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+		cfg.ErrorTemplates = map[string]string{"upstreamRefused": "% Error: {query} is down, id {request_id}"}
+		cfg.ResultPatterns = map[string]config.ResultPatterns{
+			"127.0.0.1": {Error: []string{`(?m)^You have exceeded`}},
+		}
+	})
+	defer w.cache.Close()
+
+	cl := client{requestID: "abc123"}
+	testCases := []struct {
+		query  string
+		answer string
+	}{
+		{"bad_domain.ru", "% Error: invalid query \"bad_domain.ru\": label \"bad_domain\" contains invalid character '_'\r\n% Request ID: abc123"},
+		{"https://www.-bad.ru/", "% Error: invalid query \"https://www.-bad.ru/\": label \"-bad\" starts or ends with a hyphen\r\n% Request ID: abc123"},
+		{"ru", "% Error: no whois server for \"ru\"\r\n% Request ID: abc123"},
+		{"example.ru", "% Error: query rate limit exceeded for \"example.ru\", try again later\r\n% Request ID: abc123"},
+		{"example.com", "% Error: example.com is down, id abc123"},
+	}
+
+	for n, test := range testCases {
+		answer, err := w.answerQuery(cl, test.query)
+		if err == nil || answer != test.answer {
+			t.Errorf("unexpected answer for test case #%d: %v\n%q", n, err, answer)
+		}
+	}
+
+	// legacy errorMsgTemplate для неверных запросов
+	w.cfg.ErrorMsgTemplate = "Bad request params: %s"
+	if answer, _ := w.answerQuery(cl, "bad_domain.ru"); answer != "Bad request params: bad_domain.ru" {
+		t.Errorf("unexpected legacy answer: %q", answer)
+	}
+	w.cfg.ErrorTemplates["invalidQuery"] = "% Error: bad {query}"
+	w.errorTemplates, _ = newErrorTemplates(w.cfg.ErrorTemplates)
+	if answer, _ := w.answerQuery(cl, "bad_domain.ru"); answer != "% Error: bad bad_domain.ru" {
+		t.Errorf("unexpected answer: %q", answer)
+	}
+
+	if _, err := w.answerQuery(cl, "example.ru"); !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
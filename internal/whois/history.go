@@ -0,0 +1,219 @@
+package whois
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/history"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+const HistoryPruneDefault = time.Hour
+
+func newHistory(cfg config.History) (*history.Store, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+
+	return history.Open(cfg.Path, time.Duration(cfg.Retention)*24*time.Hour)
+}
+
+// recordHistory - сохранить ответ upstream в историю (ошибки whois серверов не сохраняются)
+func (w *ProxyWhoisServer) recordHistory(fqdn string, class ResultClass, raw, upstream string, at time.Time) {
+	if w.history == nil || class == ClassError {
+		return
+	}
+
+	created, err := w.history.Record(fqdn, raw, upstream, at)
+	if err != nil {
+		w.logger.WithError(err).Warnf("history of %s not recorded", fqdn)
+		return
+	}
+	if created {
+		w.logger.Debugf("new history version of %s", fqdn)
+	}
+}
+
+// splitHistoryQuery - "example.com@2026-09-01" -> example.com, момент времени.
+// "user@example.com" (после "@" домен, а не время) - не запрос истории.
+func splitHistoryQuery(query string) (string, string, bool) {
+	i := strings.LastIndex(query, "@")
+	if i <= 0 {
+		return query, "", false
+	}
+
+	at := query[i+1:]
+	if strings.Contains(at, ".") {
+		if _, err := history.ParseTime(at); err != nil {
+			return query, "", false
+		}
+	}
+
+	return query[:i], at, true
+}
+
+// historyAnswer - ответ на запрос "domain@date" по 43 порту
+func (w *ProxyWhoisServer) historyAnswer(cl client, domain, at string) (string, error) {
+	fqdn, err := w.queryDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	fqdn = w.registrable(fqdn)
+
+	t, err := history.ParseTime(at)
+	if err != nil {
+		return "", errors.WithMessage(upstream.ErrInvalidQuery, err.Error())
+	}
+
+	v, found, err := w.history.At(fqdn, t)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return fmt.Sprintf("%% whois-proxy history: no version of %s at %s\n", fqdn, t.UTC().Format(time.RFC3339)), nil
+	}
+
+	return fmt.Sprintf("%% whois-proxy history: %s, seen from %s to %s, source %s\n%s", fqdn,
+		v.FirstSeen.UTC().Format(time.RFC3339), v.LastSeen.UTC().Format(time.RFC3339), v.Upstream,
+		w.redactor.apply(cl, fqdn, v.Raw)), nil
+}
+
+// historyLoop - периодическое удаление истории старше retention
+func (w *ProxyWhoisServer) historyLoop() {
+	if w.history == nil || w.cfg.History.Retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(durationOrDefault(w.cfg.History.Prune, HistoryPruneDefault))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			pruned, err := w.history.Prune(time.Now())
+			if err != nil {
+				w.logger.WithError(err).Error("history prune problem")
+				continue
+			}
+			w.logger.Debugf("history prune: %d versions removed", pruned)
+		}
+	}
+}
+
+// historyParams - домен и момент времени из параметров HTTP запроса
+func (w *ProxyWhoisServer) historyParams(r *http.Request, param string) (string, time.Time, error) {
+	fqdn, err := w.queryDomain(r.URL.Query().Get("domain"))
+	if err != nil {
+		return "", time.Time{}, errors.WithMessage(err, "domain is invalid")
+	}
+	fqdn = w.registrable(fqdn)
+
+	value := r.URL.Query().Get(param)
+	if value == "" {
+		return fqdn, time.Now(), nil
+	}
+
+	t, err := history.ParseTime(value)
+	if err != nil {
+		return "", time.Time{}, errors.Errorf("%s is invalid", param)
+	}
+
+	return fqdn, t, nil
+}
+
+// GET /history?domain=example.ru&at=2026-09-01 - версия ответа на момент at (по умолчанию - последняя)
+func (w *ProxyWhoisServer) handleHistory(rw http.ResponseWriter, r *http.Request) {
+	fqdn, at, err := w.historyParams(r, "at")
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v, found, err := w.history.At(fqdn, at)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(rw, "no version at this time", http.StatusNotFound)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Header().Set("Last-Modified", v.FirstSeen.UTC().Format(http.TimeFormat))
+	_, _ = rw.Write([]byte(w.redactor.apply(httpRequestClient(r), fqdn, v.Raw)))
+}
+
+// GET /history/versions?domain=example.ru - список версий (без текста ответа)
+func (w *ProxyWhoisServer) handleHistoryVersions(rw http.ResponseWriter, r *http.Request) {
+	fqdn, _, err := w.historyParams(r, "at")
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	versions, err := w.history.Versions(fqdn)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range versions {
+		versions[i].Raw = ""
+	}
+
+	writeJSON(rw, http.StatusOK, versions)
+}
+
+// GET /history/diff?domain=example.ru&from=2026-09-01&to=2026-10-01 - разница версий (to по умолчанию - последняя)
+func (w *ProxyWhoisServer) handleHistoryDiff(rw http.ResponseWriter, r *http.Request) {
+	fqdn, from, err := w.historyParams(r, "from")
+	if err == nil && r.URL.Query().Get("from") == "" {
+		err = errors.New("from is required")
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, to, err := w.historyParams(r, "to")
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vFrom, foundFrom, err := w.history.At(fqdn, from)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	vTo, foundTo, err := w.history.At(fqdn, to)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !foundFrom || !foundTo {
+		http.Error(rw, "no version at this time", http.StatusNotFound)
+		return
+	}
+
+	cl := httpRequestClient(r)
+	vFrom.Raw = w.redactor.apply(cl, fqdn, vFrom.Raw)
+	vTo.Raw = w.redactor.apply(cl, fqdn, vTo.Raw)
+
+	diff, err := history.Diff(vFrom, vTo)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = rw.Write([]byte(diff))
+}
@@ -0,0 +1,124 @@
+package whois
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func TestWhoisProxyServer_History(t *testing.T) {
+	var registrar int32
+	upstream := newFakeWhois(t, func(query string) string {
+		return "domain:        " + query + "\r\nregistrar:     REG-" + string(rune('A'+atomic.LoadInt32(&registrar))) + "\r\n"
+	})
+	defer upstream.Close()
+
+	dir, err := ioutil.TempDir("", "whois-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.ErrorMsgTemplate = "Bad request params: %s"
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+		cfg.History = config.History{Enable: true, Path: dir}
+		cfg.HTTP.Token = "token"
+	})
+	defer w.cache.Close()
+
+	// две разные версии ответа и повтор второй
+	for _, r := range []int32{0, 1, 1} {
+		atomic.StoreInt32(&registrar, r)
+		_ = w.cache.Remove("example.ru")
+		if _, err := w.lookup("example.ru"); err != nil {
+			t.Fatalf("lookup error: %v", err)
+		}
+	}
+
+	versions, _ := w.history.Versions("example.ru")
+	if len(versions) != 2 {
+		t.Fatalf("unexpected history versions count: %d", len(versions))
+	}
+
+	answer, err := w.processRequest(client{}, "example.ru@"+versions[0].LastSeen.Format("2006-01-02T15:04:05.999999999Z07:00")+"\r\n")
+	if err != nil || !strings.HasPrefix(answer, "% whois-proxy history: example.ru") || !strings.Contains(answer, "REG-A") {
+		t.Errorf("unexpected history answer: %q %v", answer, err)
+	}
+
+	answer, _ = w.processRequest(client{}, "example.ru@2000-01-01\r\n")
+	if !strings.HasPrefix(answer, "% whois-proxy history: no version of example.ru") {
+		t.Errorf("unexpected history answer: %q", answer)
+	}
+
+	answer, _ = w.processRequest(client{}, "example.ru@yesterday\r\n")
+	if answer != "Bad request params: example.ru@yesterday" {
+		t.Errorf("unexpected answer for invalid history query: %q", answer)
+	}
+
+	srv := httptest.NewServer(w.httpHandler())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("http request error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, body := get("/history?domain=example.ru"); status != http.StatusOK || !strings.Contains(body, "REG-B") {
+		t.Errorf("unexpected latest version: %d %q", status, body)
+	}
+
+	from := versions[0].FirstSeen.Format("2006-01-02T15:04:05.999999999Z07:00")
+	status, body := get("/history/diff?domain=example.ru&from=" + strings.Replace(from, "+", "%2B", -1))
+	if status != http.StatusOK || !strings.Contains(body, "- registrar:     REG-A\n+ registrar:     REG-B\n") {
+		t.Errorf("unexpected diff: %d %q", status, body)
+	}
+
+	if status, _ := get("/history?domain=example.ru&at=2000-01-01"); status != http.StatusNotFound {
+		t.Errorf("unexpected status for missing version: %d", status)
+	}
+	if status, _ := get("/history/versions?domain=test_domain.ru"); status != http.StatusBadRequest {
+		t.Errorf("unexpected status for invalid domain: %d", status)
+	}
+
+	for _, path := range []string{"/history", "/history/versions", "/history/diff"} {
+		resp, err := http.Get(srv.URL + path + "?domain=example.ru")
+		if err != nil {
+			t.Fatalf("http request error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("unexpected status without token for %s: %d", path, resp.StatusCode)
+		}
+	}
+
+	// слишком большая разница версий не строится
+	first := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for n, line := range []string{"A", "B"} {
+		var raw strings.Builder
+		for i := 0; i < 1500; i++ {
+			raw.WriteString("remarks:       " + line + strconv.Itoa(i) + "\r\n")
+		}
+		if _, err := w.history.Record("big.ru", raw.String(), "", first.Add(time.Duration(n)*time.Minute)); err != nil {
+			t.Fatalf("history not recorded: %v", err)
+		}
+	}
+	if status, body := get("/history/diff?domain=big.ru&from=" + first.UTC().Format(time.RFC3339)); status != http.StatusUnprocessableEntity {
+		t.Errorf("unexpected status for oversized diff: %d %q", status, body)
+	}
+}
@@ -0,0 +1,121 @@
+package whois
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/invalidate"
+)
+
+// httpHandler - HTTP API сервиса
+func (w *ProxyWhoisServer) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cache/invalidate", w.authorized(w.handleInvalidate))
+	mux.HandleFunc("/cache/stats", w.authorized(w.handleCacheStats))
+	mux.HandleFunc("/resolve", w.authorized(w.handleResolve))
+	mux.HandleFunc("/batch", w.handleBatch)
+	mux.HandleFunc("/available", w.handleAvailable)
+
+	if w.descInfo != nil {
+		mux.HandleFunc("/descinfo/stats", w.authorized(w.handleDescInfoStats))
+	}
+
+	if w.history != nil {
+		mux.HandleFunc("/history", w.authorized(w.handleHistory))
+		mux.HandleFunc("/history/versions", w.authorized(w.handleHistoryVersions))
+		mux.HandleFunc("/history/diff", w.authorized(w.handleHistoryDiff))
+	}
+
+	if w.invalidation != nil {
+		mux.Handle(invalidate.HTTPPath, invalidate.Handler(w.acceptInvalidation))
+	}
+
+	return mux
+}
+
+func (w *ProxyWhoisServer) startHTTP() error {
+	if w.cfg.HTTP.Listen == "" {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", w.cfg.HTTP.Listen)
+	if err != nil {
+		return errors.WithMessage(err, "can't listen http api")
+	}
+
+	w.httpServer = &http.Server{Handler: w.httpHandler()}
+	go func() {
+		if err := w.httpServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			w.logger.WithError(err).Error("http api problem")
+		}
+	}()
+	w.logger.Infof("HTTP API starts at %s", l.Addr())
+
+	return nil
+}
+
+// authorized - проверка Authorization: Bearer <http.token> (без token административные методы закрыты)
+func (w *ProxyWhoisServer) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if w.cfg.HTTP.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(w.cfg.HTTP.Token)) != 1 {
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(rw, r)
+	}
+}
+
+// POST /cache/invalidate?domain=example.ru
+func (w *ProxyWhoisServer) handleInvalidate(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		http.Error(rw, "domain is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := w.Invalidate(domain); err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// GET /cache/stats
+func (w *ProxyWhoisServer) handleCacheStats(rw http.ResponseWriter, _ *http.Request) {
+	writeJSON(rw, http.StatusOK, w.CacheStats())
+}
+
+// GET /resolve?domain=example.ru - шаги выбора whois сервера (resolvers.chain), для отладки
+func (w *ProxyWhoisServer) handleResolve(rw http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		http.Error(rw, "domain is required", http.StatusBadRequest)
+		return
+	}
+
+	trace, err := w.ResolveTrace(r.Context(), domain)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, trace)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
@@ -0,0 +1,133 @@
+package whois
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/idna"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+const (
+	IDNATransitional    = "transitional" // UTS-46 transitional: "ß" -> "ss", "ς" -> "σ" (по умолчанию)
+	IDNANonTransitional = "nontransitional"
+)
+
+// defaultIDNA - профиль без настроек idna: transitional, lookup
+var defaultIDNA = mustIDNAProfile(config.IDNAProfile{})
+
+type idnaProfiles struct {
+	global *idna.Profile
+	zones  map[string]*idna.Profile // зона в punycode
+}
+
+func newIDNAProfiles(cfg config.IDNA) (*idnaProfiles, error) {
+	global, err := newIDNAProfile(cfg.IDNAProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &idnaProfiles{global: global, zones: map[string]*idna.Profile{}}
+	for zone, profile := range cfg.Zones {
+		ascii, err := idna.Lookup.ToASCII(strings.Trim(strings.ToLower(zone), "."))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "bad idna zone %q", zone)
+		}
+		if p.zones[ascii], err = newIDNAProfile(profile); err != nil {
+			return nil, errors.WithMessagef(err, "idna profile of %s", zone)
+		}
+	}
+
+	return p, nil
+}
+
+// newIDNAProfile - перевод в punycode по UTS-46. Допустимые символы и дефисы проверяет hostname.Rules (queryValidation).
+func newIDNAProfile(cfg config.IDNAProfile) (*idna.Profile, error) {
+	opts := []idna.Option{idna.MapForLookup()}
+	if cfg.Strict {
+		// IDNA2008 для регистрации: без преобразований регистра, ширины и т.д., с проверкой длины.
+		// ValidateForRegistration всегда включает BidiRule, отключить его в idna нельзя.
+		if !cfg.Bidi {
+			return nil, errors.New("idna strict mode always applies the bidi rule, set bidi: true")
+		}
+		opts = []idna.Option{idna.ValidateForRegistration()}
+	}
+
+	switch cfg.Mode {
+	case "", IDNATransitional:
+		opts = append(opts, idna.Transitional(true))
+	case IDNANonTransitional:
+		opts = append(opts, idna.Transitional(false))
+	default:
+		return nil, errors.Errorf("unknown idna mode %q", cfg.Mode)
+	}
+
+	if cfg.Bidi && !cfg.Strict {
+		opts = append(opts, idna.BidiRule())
+	}
+
+	opts = append(opts, idna.StrictDomainName(false), idna.CheckHyphens(false))
+
+	return idna.New(opts...), nil
+}
+
+func mustIDNAProfile(cfg config.IDNAProfile) *idna.Profile {
+	p, err := newIDNAProfile(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// profile - профиль самой длинной зоны домена из idna.zones или общий профиль
+func (p *idnaProfiles) profile(domain string) *idna.Profile {
+	if p == nil {
+		return defaultIDNA
+	}
+
+	if len(p.zones) > 0 {
+		labels := strings.Split(domain, ".")
+		for i := 1; i < len(labels); i++ {
+			zone := strings.Join(labels[i:], ".")
+			if ascii, err := idna.Lookup.ToASCII(zone); err == nil {
+				zone = ascii
+			}
+			if profile, found := p.zones[zone]; found {
+				return profile
+			}
+		}
+	}
+
+	return p.global
+}
+
+// toASCII - A-label домена по профилю его зоны
+func (p *idnaProfiles) toASCII(domain string) (string, error) {
+	return p.profile(domain).ToASCII(domain)
+}
+
+// idnaNote - строка-комментарий ответа на IDN запрос: U-label и A-label, запрошенный у whois сервера.
+// Для ASCII запросов - пустая строка.
+func (p *idnaProfiles) idnaNote(query, fqdn string) string {
+	if !strings.Contains(fqdn, "xn--") && isASCII(query) {
+		return ""
+	}
+
+	unicode, err := p.profile(fqdn).ToUnicode(fqdn)
+	if err != nil {
+		unicode = fqdn
+	}
+
+	return fmt.Sprintf("%% whois-proxy: U-label %s, A-label %s\r\n", unicode, fqdn)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package whois
+
+import (
+	"testing"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func TestIDNAProfiles(t *testing.T) {
+	p, err := newIDNAProfiles(config.IDNA{
+		Zones: map[string]config.IDNAProfile{
+			"de": {Mode: IDNANonTransitional},
+			"рф": {Mode: IDNANonTransitional, Bidi: true},
+			"il": {Bidi: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("idna profiles not created: %v", err)
+	}
+
+	testCases := []struct {
+		domain string
+		ascii  string
+		valid  bool
+	}{
+		{"straße.com", "strasse.com", true},
+		{"straße.de", "xn--strae-oqa.de", true},
+		{"sub.straße.de", "sub.xn--strae-oqa.de", true},
+		{"окна.рф", "xn--80atjc.xn--p1ai", true},
+		{"окна.xn--p1ai", "xn--80atjc.xn--p1ai", true},
+		{"aא.com", "xn--a-0hc.com", true},
+		{"aא.il", "", false},
+	}
+
+	for n, test := range testCases {
+		ascii, err := p.toASCII(test.domain)
+		if test.valid != (err == nil) || (test.valid && ascii != test.ascii) {
+			t.Errorf("unexpected result for test case #%d: %q %v", n, ascii, err)
+		}
+	}
+
+	// final sigma: "ς" -> "σ" только в transitional
+	transitional, _ := p.toASCII("ελληνικός.com")
+	nontransitional, _ := p.toASCII("ελληνικός.de")
+	if transitional == "" || transitional[:len(transitional)-len(".com")] == nontransitional[:len(nontransitional)-len(".de")] {
+		t.Errorf("final sigma mapped the same way: %s %s", transitional, nontransitional)
+	}
+
+	if _, err := newIDNAProfiles(config.IDNA{IDNAProfile: config.IDNAProfile{Mode: "uts46"}}); err == nil {
+		t.Error("no error for unknown idna mode")
+	}
+	if _, err := newIDNAProfiles(config.IDNA{Zones: map[string]config.IDNAProfile{"de": {Mode: "bad"}}}); err == nil {
+		t.Error("no error for unknown zone idna mode")
+	}
+
+	strict, err := newIDNAProfiles(config.IDNA{IDNAProfile: config.IDNAProfile{Strict: true, Bidi: true, Mode: IDNANonTransitional}})
+	if err != nil {
+		t.Fatalf("idna profiles not created: %v", err)
+	}
+	if _, err := strict.toASCII("ＥＸＡＭＰＬＥ.com"); err == nil {
+		t.Error("no error for unmapped fullwidth characters in strict mode")
+	}
+	if _, err := p.toASCII("ＥＸＡＭＰＬＥ.com"); err != nil {
+		t.Errorf("unexpected error for fullwidth characters: %v", err)
+	}
+
+	// правила регистрации всегда с bidi, strict без bidi - ошибка конфигурации
+	if _, err := newIDNAProfiles(config.IDNA{IDNAProfile: config.IDNAProfile{Strict: true}}); err == nil {
+		t.Error("no error for strict profile without bidi")
+	}
+}
+
+func TestIDNAProfiles_default(t *testing.T) {
+	p, err := newIDNAProfiles(config.IDNA{})
+	if err != nil {
+		t.Fatalf("idna profiles not created: %v", err)
+	}
+
+	testCases := []struct {
+		domain string
+		result string
+	}{
+		{"example.com", "example.com"},
+		{"test.example.com", "test.example.com"},
+		{"test.test.example.com", "test.test.example.com"},
+		{"окна.рф", "xn--80atjc.xn--p1ai"},
+		{"a.bc", "a.bc"},
+		{"xn--kxae4bafwg.xn--pxaix.gr", "xn--kxae4bafwg.xn--pxaix.gr"},
+		{"subdomain.subdomainsubdomainsuèdomainsubdomainsubdomainsubdomainsubdomain.net",
+			"subdomain.xn--subdomainsubdomainsudomainsubdomainsubdomainsubdomainsubdomain-1mf.net"},
+	}
+
+	for n, test := range testCases {
+		puny, err := p.toASCII(test.domain)
+		if err != nil {
+			t.Errorf("unexpected error for test case #%d: %v", n, err)
+		}
+		if puny != test.result {
+			t.Errorf("unxpected result for test case #%d: %s <> %s", n, puny, test.result)
+		}
+	}
+}
+
+func TestWhoisProxyServer_idnaNote(t *testing.T) {
+	upstream := newFakeWhois(t, func(query string) string { return "domain: " + query + "\r\n" })
+	defer upstream.Close()
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"de": upstream.Addr(), "ru": upstream.Addr()}
+		cfg.IDNA = config.IDNA{Zones: map[string]config.IDNAProfile{"de": {Mode: IDNANonTransitional}}}
+	})
+	defer w.cache.Close()
+
+	testCases := []struct {
+		query  string
+		answer string
+	}{
+		{"straße.de", "% whois-proxy: U-label straße.de, A-label xn--strae-oqa.de\r\ndomain: xn--strae-oqa.de\r\n"},
+		{"xn--strae-oqa.de", "% whois-proxy: U-label straße.de, A-label xn--strae-oqa.de\r\ndomain: xn--strae-oqa.de\r\n"},
+		{"example.ru", "domain: example.ru\r\n"},
+	}
+
+	for n, test := range testCases {
+		if answer, err := w.answerQuery(client{}, test.query); err != nil || answer != test.answer {
+			t.Errorf("unexpected answer for test case #%d: %q %v", n, answer, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package whois
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/invalidate"
+)
+
+// invalidation - рассылка удаления ключей кэша между репликами
+type invalidation struct {
+	bus        *invalidate.Bus
+	transports []invalidate.Transport
+	multicast  *invalidate.Multicast
+}
+
+func newInvalidation(cfg *config.Service) (*invalidation, error) {
+	if !cfg.Invalidation.Enable {
+		return nil, nil
+	}
+
+	origin := cfg.Peers.Self
+	if origin == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't get hostname for invalidation origin")
+		}
+		origin = hostname + ":" + cfg.Port
+	}
+
+	bus, err := invalidate.NewBus(cfg.Invalidation.Secret, origin, time.Duration(cfg.Invalidation.MaxSkew)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &invalidation{bus: bus}
+
+	if cfg.Invalidation.Multicast != "" {
+		inv.multicast, err = invalidate.NewMulticast(cfg.Invalidation.Multicast, cfg.Invalidation.Interface)
+		if err != nil {
+			return nil, err
+		}
+		inv.transports = append(inv.transports, inv.multicast)
+	}
+
+	if len(cfg.Invalidation.Peers) > 0 {
+		timeout := time.Duration(cfg.Invalidation.Timeout) * time.Millisecond
+		inv.transports = append(inv.transports, invalidate.NewHTTPPeers(cfg.Invalidation.Peers, timeout))
+	}
+
+	return inv, nil
+}
+
+// Invalidate - удалить запись из кэша этой реплики и разослать удаление остальным.
+// Удаляется запись регистрируемого домена, запись самого запроса (сохраненная до сокращения запросов по Public Suffix List)
+// и результат проверки доступности домена.
+func (w *ProxyWhoisServer) Invalidate(query string) error {
+	fqdn, err := w.queryDomain(query)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{w.registrable(fqdn)}
+	if keys[0] != fqdn {
+		keys = append(keys, fqdn)
+	}
+	keys = append(keys, availabilityKeyPrefix+keys[0])
+
+	var publishErr error
+	for _, key := range keys {
+		if err := w.cache.Remove(key); err != nil {
+			return errors.WithMessagef(err, "can't remove %s from cache", key)
+		}
+		w.logger.Infof("cache entry %s invalidated", key)
+
+		if w.invalidation == nil {
+			continue
+		}
+
+		msg := w.invalidation.bus.New(key)
+		for _, t := range w.invalidation.transports {
+			if err := t.Publish(msg); err != nil {
+				publishErr = err
+				w.logger.WithError(err).Warnf("invalidation of %s not published", key)
+			}
+		}
+	}
+
+	return publishErr
+}
+
+// acceptInvalidation - сообщение от другой реплики, повторы и свои сообщения игнорируются
+func (w *ProxyWhoisServer) acceptInvalidation(msg invalidate.Message) error {
+	accepted, err := w.invalidation.bus.Accept(msg)
+	if err != nil {
+		w.logger.WithError(err).Warnf("invalidation message from %s rejected", msg.Origin)
+		return err
+	}
+	if !accepted {
+		return nil
+	}
+
+	if err := w.cache.Remove(msg.Key); err != nil {
+		return errors.WithMessagef(err, "can't remove %s from cache", msg.Key)
+	}
+	w.logger.Infof("cache entry %s invalidated by %s", msg.Key, msg.Origin)
+
+	return nil
+}
+
+func (w *ProxyWhoisServer) startInvalidation() error {
+	if w.invalidation == nil || w.invalidation.multicast == nil {
+		return nil
+	}
+
+	return w.invalidation.multicast.Listen(w.stop, func(msg invalidate.Message) {
+		_ = w.acceptInvalidation(msg)
+	}, func(err error) {
+		w.logger.WithError(err).Warn("invalidation multicast problem")
+	})
+}
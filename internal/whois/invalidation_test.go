@@ -0,0 +1,73 @@
+package whois
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+func newInvalidationTestServer(t *testing.T, self string, peers []string) *ProxyWhoisServer {
+	server := newTestServer(t, func(cfg *config.Service) {
+		cfg.Peers = config.Peers{Self: self}
+		cfg.HTTP = config.HTTP{Token: "token"}
+		cfg.Invalidation = config.Invalidation{Enable: true, Secret: "secret", Peers: peers, Timeout: 500}
+	})
+
+	return server
+}
+
+func TestWhoisProxyServer_Invalidate(t *testing.T) {
+	b := newInvalidationTestServer(t, "b", nil)
+	defer b.cache.Close()
+	srvB := httptest.NewServer(b.httpHandler())
+	defer srvB.Close()
+
+	a := newInvalidationTestServer(t, "a", []string{srvB.URL})
+	defer a.cache.Close()
+	srvA := httptest.NewServer(a.httpHandler())
+	defer srvA.Close()
+
+	for _, w := range []*ProxyWhoisServer{a, b} {
+		_ = w.cache.SetEntry("test.domain.ru", storage.Entry{Raw: "whois info", FetchedAt: time.Now()})
+		_ = w.cache.SetEntry(availabilityKeyPrefix+"domain.ru", storage.Entry{Raw: "no not found pattern matched", FetchedAt: time.Now()})
+	}
+
+	request := func(token, domain string) int {
+		req, _ := http.NewRequest(http.MethodPost, srvA.URL+"/cache/invalidate?domain="+domain, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("http request error: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := request("wrong", "test.domain.ru"); status != http.StatusUnauthorized {
+		t.Errorf("unexpected status without token: %d", status)
+	}
+	if _, found, _ := b.cache.GetEntry("test.domain.ru"); !found {
+		t.Fatalf("entry removed without authorization")
+	}
+
+	if status := request("token", "test_domain.ru"); status != http.StatusBadRequest {
+		t.Errorf("unexpected status for invalid domain: %d", status)
+	}
+
+	if status := request("token", "TEST.domain.ru"); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+
+	for name, w := range map[string]*ProxyWhoisServer{"a": a, "b": b} {
+		if _, found, _ := w.cache.GetEntry("test.domain.ru"); found {
+			t.Errorf("entry not invalidated on replica %s", name)
+		}
+		if _, found, _ := w.cache.GetEntry(availabilityKeyPrefix + "domain.ru"); found {
+			t.Errorf("availability result not invalidated on replica %s", name)
+		}
+	}
+}
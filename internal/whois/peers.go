@@ -0,0 +1,173 @@
+package whois
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/peer"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/server"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+const (
+	PeerTimeoutDefault    = 5 * time.Second
+	PeerDNSRefreshDefault = 30 * time.Second
+)
+
+// peerSet - реплики whois-proxy, между которыми распределены ключи кэша
+type peerSet struct {
+	cfg     config.Peers
+	ring    *peer.Ring
+	auth    peer.Auth
+	timeout time.Duration
+	server  *server.Server
+}
+
+func newPeers(cfg config.Peers, maxCntConnect int) (*peerSet, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+
+	if cfg.Self == "" || cfg.Listen == "" {
+		return nil, errors.New("peers: self and listen addresses are required")
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		return nil, errors.WithMessage(err, "peers: bad listen address")
+	}
+
+	srv, err := server.New(server.TCP, host, port, maxCntConnect)
+	if err != nil {
+		return nil, errors.WithMessage(err, "peers: can't create tcp server")
+	}
+
+	nodes, err := peer.Discover(staticPeers(cfg), cfg.DNSName, cfg.DNSPort)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := PeerTimeoutDefault
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	}
+
+	return &peerSet{
+		cfg:     cfg,
+		ring:    peer.NewRing(cfg.Replicas, nodes...),
+		auth:    peer.NewAuth(cfg.Secret),
+		timeout: timeout,
+		server:  srv,
+	}, nil
+}
+
+// staticPeers - статический список реплик вместе с этой (копия, cfg.Static не меняется)
+func staticPeers(cfg config.Peers) []string {
+	nodes := make([]string, 0, len(cfg.Static)+1)
+	return append(append(nodes, cfg.Static...), cfg.Self)
+}
+
+// owner - реплика-владелец ключа, "" - ключ принадлежит этой реплике
+func (p *peerSet) owner(fqdn string) string {
+	if p == nil {
+		return ""
+	}
+
+	owner := p.ring.Owner(fqdn)
+	if owner == p.cfg.Self {
+		return ""
+	}
+	return owner
+}
+
+func (w *ProxyWhoisServer) startPeers(chErr chan<- error) error {
+	if w.peers == nil {
+		return nil
+	}
+
+	err := w.peers.server.ListenAndServe(peer.Handler(w.peerFetch, w.peers.auth, w.peers.timeout), chErr)
+	if err != nil {
+		return errors.WithMessage(err, "can't ListenAndServe for peers")
+	}
+	w.logger.Infof("Peers protocol starts at %s, peers: %v", w.peers.server.Addr(), w.peers.ring.Nodes())
+	if w.peers.cfg.Secret == "" {
+		w.logger.Warn("peers secret is not set: anyone who can reach the peers listener can read the cache, bind it to a private interface")
+	}
+
+	go w.peersDiscoveryLoop()
+
+	return nil
+}
+
+// peerFetch - запрос от другой реплики: этот экземпляр владелец ключа, дальше не пересылаем
+func (w *ProxyWhoisServer) peerFetch(fqdn string) (storage.Entry, error) {
+	entry, found, err := w.cache.GetEntry(fqdn)
+	if err != nil {
+		w.logger.WithError(err).Warn("cache get problem")
+	}
+	if found {
+		return entry, nil
+	}
+
+	host, port, err := w.getWhoisServer(context.Background(), fqdn)
+	if err != nil {
+		return storage.Entry{}, errors.WithMessagef(err, "error while getWhoisServer()")
+	}
+
+	return w.fetchAndCache(context.Background(), fqdn, host, port, nil)
+}
+
+// fetchFromOwner - получить запись у реплики-владельца. При ошибке - false, идем к whois серверу сами.
+// Таймаут запроса к реплике не больше оставшегося срока ctx.
+func (w *ProxyWhoisServer) fetchFromOwner(ctx context.Context, fqdn string) (storage.Entry, bool) {
+	owner := w.peers.owner(fqdn)
+	if owner == "" {
+		return storage.Entry{}, false
+	}
+
+	timeout, ok := remaining(ctx, w.peers.timeout)
+	if !ok {
+		return storage.Entry{}, false
+	}
+
+	entry, err := peer.Fetch(owner, fqdn, w.peers.auth, timeout, peer.MaxReplyLen(w.maxResponseSize()))
+	if err != nil {
+		w.logger.WithError(err).Warnf("peer fetch of %s failed, fallback to upstream", fqdn)
+		return storage.Entry{}, false
+	}
+	w.logger.Debugf("%s fetched from peer %s", fqdn, owner)
+
+	// сохраняем локально с оставшимся у владельца TTL
+	if err := w.cache.SetEntry(fqdn, entry); err != nil {
+		w.logger.WithError(err).Warn("cache set problem")
+	}
+
+	return entry, true
+}
+
+func (w *ProxyWhoisServer) peersDiscoveryLoop() {
+	if w.peers.cfg.DNSName == "" {
+		return
+	}
+
+	ticker := time.NewTicker(durationOrDefault(w.peers.cfg.DNSRefresh, PeerDNSRefreshDefault))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			nodes, err := peer.Discover(staticPeers(w.peers.cfg), w.peers.cfg.DNSName, w.peers.cfg.DNSPort)
+			if err != nil {
+				w.logger.WithError(err).Warn("peers discovery problem")
+				continue
+			}
+			w.peers.ring.Set(nodes...)
+		}
+	}
+}
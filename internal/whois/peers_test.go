@@ -0,0 +1,91 @@
+package whois
+
+import (
+	"fmt"
+	"testing"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func newPeerTestServer(t *testing.T, port, self string, static []string, upstream string) *ProxyWhoisServer {
+	server := newTestServer(t, func(cfg *config.Service) {
+		cfg.Port = port
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream}
+		cfg.Peers = config.Peers{Enable: true, Self: self, Listen: self, Static: static, Timeout: 500, Secret: "secret"}
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("error while server.Start. err: %v", err)
+	}
+	waitServing(t, server.server.Addr())
+	waitServing(t, server.peers.server.Addr())
+
+	return server
+}
+
+func TestWhoisProxyServer_Peers(t *testing.T) {
+	upstream := newFakeWhois(t, func(query string) string {
+		return "domain:        " + query + "\r\n"
+	})
+	defer upstream.Close()
+
+	peerA, peerB := "127.0.0.1:50130", "127.0.0.1:50131"
+	static := []string{peerA, peerB}
+
+	a := newPeerTestServer(t, "50132", peerA, static, upstream.Addr())
+	defer a.Stop()
+	b := newPeerTestServer(t, "50133", peerB, static, upstream.Addr())
+	defer b.Stop()
+
+	// домен, владелец которого - реплика B
+	var fqdn string
+	for i := 0; fqdn == ""; i++ {
+		if candidate := fmt.Sprintf("domain%d.ru", i); a.peers.ring.Owner(candidate) == peerB {
+			fqdn = candidate
+		}
+	}
+
+	res, err := a.lookup(fqdn)
+	if err != nil || res.entry.Raw != "domain:        "+fqdn+"\r\n" {
+		t.Fatalf("unexpected lookup through peer: %+v %v", res, err)
+	}
+
+	// B получил запись от whois сервера и закэшировал ее, A - от B
+	res, err = b.lookup(fqdn)
+	if err != nil || !res.cached {
+		t.Errorf("owner has no cached entry: %+v %v", res, err)
+	}
+	if queries := upstream.Queries(); len(queries) != 1 {
+		t.Errorf("unexpected upstream queries: %v", queries)
+	}
+
+	// владелец недоступен - A идет к whois серверу сам
+	_ = b.Stop()
+
+	var other string
+	for i := 1000; other == ""; i++ {
+		if candidate := fmt.Sprintf("domain%d.ru", i); a.peers.ring.Owner(candidate) == peerB {
+			other = candidate
+		}
+	}
+
+	if res, err := a.lookup(other); err != nil || res.entry.Raw != "domain:        "+other+"\r\n" {
+		t.Errorf("no fallback to upstream: %+v %v", res, err)
+	}
+	if queries := upstream.Queries(); len(queries) != 2 {
+		t.Errorf("unexpected upstream queries after fallback: %v", queries)
+	}
+}
+
+func Test_staticPeers(t *testing.T) {
+	static := make([]string, 1, 2)
+	static[0] = "10.0.0.1:4344"
+
+	nodes := staticPeers(config.Peers{Static: static, Self: "10.0.0.2:4344"})
+	if len(nodes) != 2 || nodes[1] != "10.0.0.2:4344" {
+		t.Errorf("unexpected nodes: %v", nodes)
+	}
+	if static[:2][1] != "" {
+		t.Errorf("config slice modified: %v", static[:2])
+	}
+}
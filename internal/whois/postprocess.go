@@ -0,0 +1,119 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/rewrite"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+// client - параметры клиентского соединения, от которых может зависеть пост-обработка ответа
+type client struct {
+	ctx context.Context // контекст запроса клиента (HTTP - отменяется при разрыве соединения), nil - без отмены
+
+	listener net.Addr // локальный адрес (listener), на который пришел запрос
+	remote   net.Addr
+
+	requestID string          // идентификатор запроса в ответах об ошибках и логах
+	stream    *responseStream // TCP: ответ по мере чтения из whois сервера, nil - только целиком
+}
+
+// postProcess - обработка сырого ответа whois сервера при каждой выдаче клиенту.
+// В кэше хранится только сырой ответ, поэтому изменения конфигурации применяются сразу.
+func (w *ProxyWhoisServer) postProcess(cl client, fqdn string, entry storage.Entry) (string, error) {
+	// персональные данные скрываются до добавления собственных строк
+	whoisInfo := w.redactor.apply(cl, fqdn, entry.Raw)
+
+	in := rewrite.Input{Domain: fqdn, Upstream: entry.Upstream, Raw: entry.Raw}
+
+	// Add Beget custom fields for whois: строки внешнего источника вставляются первыми,
+	// строки addWhoisDescInfo (правила rewriter) - перед ними, сразу после "source:"
+	if external, found := w.descInfo.Lookup(fqdn); found {
+		whoisInfo = descInfoInserter.Apply(whoisInfo, external)
+	}
+
+	whoisInfo = w.rewriter.Apply(in, whoisInfo)
+
+	if w.cfg.CacheAnnotation {
+		whoisInfo = cacheAnnotation(entry) + whoisInfo
+	}
+
+	return whoisInfo, nil
+}
+
+// cacheAnnotation - строка-комментарий о свежести ответа для службы поддержки
+func cacheAnnotation(entry storage.Entry) string {
+	source := entry.Upstream
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+
+	return fmt.Sprintf("%% cached by whois-proxy, age %ds, source %s\n", int64(entry.Age()/time.Second), source)
+}
+
+// descInfoInserter - строки внешнего источника desc info после каждой строки "source:"
+var descInfoInserter = mustInserter(rewrite.InsertAfter, legacyDescAnchor)
+
+const legacyDescAnchor = `^source:`
+
+func mustInserter(typ, anchor string) *rewrite.Inserter {
+	i, err := rewrite.NewInserter(typ, anchor)
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// legacyDescRules - addWhoisDescInfo как правила rewrite: строки после каждой строки "source:" ответа по домену
+func legacyDescRules(addInfo map[string][]string) []rewrite.Rule {
+	domains := make([]string, 0, len(addInfo))
+	for fqdn := range addInfo {
+		domains = append(domains, fqdn)
+	}
+	sort.Strings(domains)
+
+	rules := make([]rewrite.Rule, 0, len(domains))
+	for _, fqdn := range domains {
+		if len(addInfo[fqdn]) == 0 {
+			continue
+		}
+		rules = append(rules, rewrite.Rule{
+			Name:    "addWhoisDescInfo " + fqdn,
+			Match:   rewrite.Match{Domains: []string{fqdn}},
+			Actions: []rewrite.Action{{Type: rewrite.InsertAfter, Anchor: legacyDescAnchor, Lines: addInfo[fqdn]}},
+		})
+	}
+	return rules
+}
+
+// newRewriter - правила addWhoisDescInfo и rewrite из конфигурации, компилируются один раз
+func newRewriter(addInfo map[string][]string, rules []config.RewriteRule) (*rewrite.Engine, error) {
+	converted := legacyDescRules(addInfo)
+	for _, r := range rules {
+		rule := rewrite.Rule{
+			Name: r.Name,
+			Match: rewrite.Match{
+				Domains:   r.Match.Domains,
+				Zones:     r.Match.Zones,
+				Upstreams: r.Match.Upstreams,
+				Fields:    r.Match.Fields,
+			},
+		}
+		for _, a := range r.Actions {
+			rule.Actions = append(rule.Actions, rewrite.Action{
+				Type:        a.Type,
+				Anchor:      a.Anchor,
+				Lines:       a.Lines,
+				Replacement: a.Replacement,
+			})
+		}
+		converted = append(converted, rule)
+	}
+
+	return rewrite.New(converted)
+}
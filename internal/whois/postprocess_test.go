@@ -0,0 +1,90 @@
+package whois
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+func TestWhoisProxyServer_PostProcessOnRead(t *testing.T) {
+	const raw = "domain:        EXAMPLE.RU\nsource:        TCI\n"
+
+	server := newTestServer(t, func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"ru": "whois.tcinet.ru:43"}
+		cfg.AddWhoisDescInfo = map[string][]string{"example.ru": {"descr:         first descr"}}
+	})
+	defer server.Stop()
+
+	_ = server.cache.SetEntry("example.ru", storage.Entry{Raw: raw, Upstream: "whois.tcinet.ru:43"})
+
+	response, err := server.processRequest(client{}, "example.ru\r\n")
+	if err != nil || !strings.Contains(response, "source:        TCI\ndescr:         first descr\n") {
+		t.Fatalf("custom info not added: %q %v", response, err)
+	}
+
+	if entry, _, _ := server.cache.GetEntry("example.ru"); entry.Raw != raw {
+		t.Errorf("cache holds post processed whois: %q", entry.Raw)
+	}
+
+	// новая конфигурация применяется к сырому ответу из кэша без его сброса
+	cfg := newTestConfig(func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"ru": "whois.tcinet.ru:43"}
+		cfg.AddWhoisDescInfo = map[string][]string{"example.ru": {"descr:         second descr"}}
+	})
+	restarted, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	defer restarted.Stop()
+
+	entry, _, _ := server.cache.GetEntry("example.ru")
+	_ = restarted.cache.SetEntry("example.ru", entry)
+
+	response, err = restarted.processRequest(client{}, "example.ru\r\n")
+	if err != nil || !strings.Contains(response, "second descr") || strings.Contains(response, "first descr") {
+		t.Errorf("custom info not applied on read: %q %v", response, err)
+	}
+}
+
+func Test_cacheAnnotation(t *testing.T) {
+	entry := storage.Entry{FetchedAt: time.Now().Add(-time.Second * 123), Upstream: "whois.tcinet.ru:43"}
+
+	if s := cacheAnnotation(entry); s != "% cached by whois-proxy, age 123s, source whois.tcinet.ru\n" {
+		t.Errorf("unexpected cache annotation: %q", s)
+	}
+}
+
+func TestWhoisProxyServer_PostProcessRewrite(t *testing.T) {
+	cfg := newTestConfig(func(cfg *config.Service) {
+		cfg.AddWhoisDescInfo = map[string][]string{"example.ru": {"descr:         legacy descr"}}
+		cfg.Rewrite = []config.RewriteRule{{
+			Name:    "beget footer",
+			Match:   config.RewriteMatch{Fields: map[string]string{"registrar": "BEGET-RU"}},
+			Actions: []config.RewriteAction{{Type: "appendFooter", Lines: []string{"% hosted by Beget"}}},
+		}}
+	})
+
+	server, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	defer server.Stop()
+
+	entry := storage.Entry{Raw: "domain:        EXAMPLE.RU\nregistrar:     BEGET-RU\nsource:        TCI\n"}
+	response, err := server.postProcess(client{}, "example.ru", entry)
+	expected := "domain:        EXAMPLE.RU\nregistrar:     BEGET-RU\nsource:        TCI\n" +
+		"descr:         legacy descr\n% hosted by Beget\n"
+	if err != nil || response != expected {
+		t.Errorf("unexpected rewrite result: %q %v", response, err)
+	}
+
+	cfg.Rewrite[0].Actions[0].Type = "unknown"
+	if _, err := NewWhoisProxyServer(cfg, &logrus.Logger{}); err == nil {
+		t.Errorf("no error for bad rewrite rule")
+	}
+}
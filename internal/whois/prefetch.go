@@ -0,0 +1,109 @@
+package whois
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	PrefetchIntervalDefault = 10 * time.Second
+	PrefetchWindowDefault   = 30 * time.Second
+	PrefetchMinHitsDefault  = 10
+	PrefetchBudgetDefault   = 50
+)
+
+// hotKeysCache - кэш, умеющий считать обращения к ключам (memory backend)
+type hotKeysCache interface {
+	HotEntries(minHits uint64, window time.Duration, limit int) []string
+}
+
+// jitterTTL - случайно уменьшить TTL на величину до prefetch.jitter процентов,
+// чтобы записи, сохраненные одновременно, не устаревали (и не обновлялись) одновременно
+func (w *ProxyWhoisServer) jitterTTL(ttl time.Duration) time.Duration {
+	jitter := w.cfg.Prefetch.Jitter
+	if jitter <= 0 || jitter >= 100 || ttl <= 0 {
+		return ttl
+	}
+
+	max := int64(ttl) * int64(jitter) / 100
+	if max <= 0 {
+		return ttl
+	}
+
+	return ttl - time.Duration(rand.Int63n(max)) //nolint:gosec
+}
+
+// prefetchLoop - refresh-ahead: популярные записи перезапрашиваются у whois сервера незадолго до устаревания,
+// не более prefetch.budget запросов за interval
+func (w *ProxyWhoisServer) prefetchLoop() {
+	hot, ok := w.cache.(hotKeysCache)
+	if !ok || !w.cfg.Prefetch.Enable {
+		return
+	}
+
+	interval := durationOrDefault(w.cfg.Prefetch.Interval, PrefetchIntervalDefault)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.prefetch(hot)
+		}
+	}
+}
+
+func (w *ProxyWhoisServer) prefetch(hot hotKeysCache) int {
+	minHits := w.cfg.Prefetch.MinHits
+	if minHits <= 0 {
+		minHits = PrefetchMinHitsDefault
+	}
+
+	budget := w.cfg.Prefetch.Budget
+	if budget <= 0 {
+		budget = PrefetchBudgetDefault
+	}
+
+	window := durationOrDefault(w.cfg.Prefetch.Window, PrefetchWindowDefault)
+
+	refreshed := 0
+	for _, fqdn := range hot.HotEntries(uint64(minHits), window, budget) {
+		select {
+		case <-w.stop:
+			return refreshed
+		default:
+		}
+
+		// результаты проверки доступности не обновляются заранее, у них свой короткий TTL
+		if isAvailabilityKey(fqdn) {
+			continue
+		}
+
+		host, port, err := w.getWhoisServer(context.Background(), fqdn)
+		if err != nil {
+			continue
+		}
+
+		if _, err := w.fetchAndCache(context.Background(), fqdn, host, port, nil); err != nil {
+			w.logger.WithError(err).Warnf("prefetch of %s failed", fqdn)
+			continue
+		}
+		refreshed++
+	}
+
+	if refreshed > 0 {
+		w.logger.Debugf("prefetch: refreshed %d hot cache entries", refreshed)
+	}
+
+	return refreshed
+}
+
+func durationOrDefault(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,60 @@
+package whois
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+func TestWhoisProxyServer_Prefetch(t *testing.T) {
+	upstream := newFakeWhois(t, func(query string) string {
+		return "domain:        " + query + "\r\nsource:        TCI\r\n"
+	})
+	defer upstream.Close()
+
+	server := newTestServer(t, func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+		cfg.Prefetch = config.Prefetch{Enable: true, Window: 60, MinHits: 2, Budget: 1}
+	})
+	defer server.Stop()
+
+	old := time.Now().Add(-time.Minute)
+	for _, fqdn := range []string{"hot.ru", "hotter.ru", "cold.ru"} {
+		_ = server.cache.SetEntry(fqdn, storage.Entry{Raw: "old", FetchedAt: old, TTL: time.Minute + time.Second*10})
+	}
+	for i := 0; i < 3; i++ {
+		_, _, _ = server.cache.GetEntry("hot.ru")
+		_, _, _ = server.cache.GetEntry("hotter.ru")
+	}
+	_, _, _ = server.cache.GetEntry("hotter.ru")
+
+	if refreshed := server.prefetch(server.cache.(hotKeysCache)); refreshed != 1 {
+		t.Fatalf("unexpected refreshed count (budget 1): %d", refreshed)
+	}
+
+	if queries := upstream.Queries(); len(queries) != 1 || queries[0] != "hotter.ru" {
+		t.Errorf("unexpected upstream queries: %v", queries)
+	}
+
+	entry, _, _ := server.cache.GetEntry("hotter.ru")
+	if entry.Raw == "old" || entry.Upstream != upstream.Addr() || entry.Age() > time.Second {
+		t.Errorf("hot entry not refreshed: %+v", entry)
+	}
+}
+
+func Test_jitterTTL(t *testing.T) {
+	server := &ProxyWhoisServer{cfg: &config.Service{Prefetch: config.Prefetch{Jitter: 10}}}
+
+	for i := 0; i < 100; i++ {
+		if ttl := server.jitterTTL(time.Minute); ttl > time.Minute || ttl < time.Second*54 {
+			t.Fatalf("jittered ttl out of range: %s", ttl)
+		}
+	}
+
+	server.cfg.Prefetch.Jitter = 0
+	if ttl := server.jitterTTL(time.Minute); ttl != time.Minute {
+		t.Errorf("ttl changed without jitter: %s", ttl)
+	}
+}
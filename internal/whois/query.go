@@ -0,0 +1,92 @@
+package whois
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/hostname"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/psl"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+const PublicSuffixRefreshDefault = time.Hour
+
+// queryDomain - домен запроса: нормализация (URL, e-mail, "www.", регистр), punycode по профилю idna зоны
+// и проверка по queryValidation.
+// Ошибка - errInvalidQuery с причиной отказа.
+func (w *ProxyWhoisServer) queryDomain(query string) (string, error) {
+	v := w.cfg.QueryValidation
+
+	fqdn, err := w.idna.toASCII(hostname.Normalize(query, v.KeepWWW))
+	if err != nil {
+		return "", errors.WithMessage(upstream.ErrInvalidQuery, err.Error())
+	}
+
+	rules := hostname.Rules{
+		MaxLength:         v.MaxLength,
+		MaxLabelLength:    v.MaxLabelLength,
+		MinTLDLength:      v.MinTLDLength,
+		AllowUnderscore:   v.AllowUnderscore,
+		AllowLooseHyphens: v.AllowLooseHyphens,
+	}
+	if err := rules.Validate(fqdn); err != nil {
+		return "", errors.WithMessage(upstream.ErrInvalidQuery, err.Error())
+	}
+
+	return fqdn, nil
+}
+
+func newPublicSuffix(cfg config.PublicSuffix) (*psl.Source, error) {
+	return psl.NewSource(cfg.File)
+}
+
+// registrable - регистрируемый домен (shop.example.co.uk -> example.co.uk): запрос к whois серверу и ключ кэша.
+// Публичный суффикс (co.uk, ru) не меняется.
+func (w *ProxyWhoisServer) registrable(fqdn string) string {
+	domain, _ := w.suffixes.List().Registrable(fqdn)
+	return domain
+}
+
+// domainZones - зоны домена от публичного суффикса: shop.example.co.uk -> [co.uk uk], co.uk -> [uk]
+func (w *ProxyWhoisServer) domainZones(fqdn string) []string {
+	return getPossibleDomainZone(w.registrable(fqdn))
+}
+
+// reducedNote - строка-комментарий ответа на запрос, сокращенный до регистрируемого домена
+func reducedNote(query, fqdn string) string {
+	return fmt.Sprintf("%% whois-proxy: query %s, answer for registrable domain %s\r\n", query, fqdn)
+}
+
+// publicSuffixLoop - перечитывание файла Public Suffix List при изменении
+func (w *ProxyWhoisServer) publicSuffixLoop() {
+	if w.cfg.PublicSuffix.File == "" {
+		return
+	}
+
+	interval := time.Duration(w.cfg.PublicSuffix.Refresh) * time.Second
+	if interval <= 0 {
+		interval = PublicSuffixRefreshDefault
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			reloaded, err := w.suffixes.Reload()
+			if err != nil {
+				w.logger.WithError(err).Error("public suffix list reload problem")
+				continue
+			}
+			if reloaded {
+				w.logger.Infof("public suffix list reloaded: %d rules", w.suffixes.List().Rules())
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package whois
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+const RedactionReplacementDefault = "REDACTED FOR PRIVACY"
+
+// fieldLine - "   Registrant Email:  user@example.com"
+var fieldLine = regexp.MustCompile(`^(\s*)([^:]+?)(:[ \t]*)(\S.*?)(\r?)$`)
+
+type (
+	// redactor - скрытие персональных данных, правила выбираются по listener, CIDR клиента и зоне
+	redactor struct {
+		trusted []*net.IPNet
+		rules   []redactRule
+	}
+
+	redactRule struct {
+		name        string
+		listeners   []string
+		clients     []*net.IPNet
+		zones       []string
+		fields      map[string]bool // lower case
+		patterns    []*regexp.Regexp
+		replacement string
+	}
+)
+
+func newRedactor(cfg config.Redaction) (*redactor, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+
+	replacement := cfg.Replacement
+	if replacement == "" {
+		replacement = RedactionReplacementDefault
+	}
+
+	trusted, err := parseCIDRs(cfg.Trusted)
+	if err != nil {
+		return nil, errors.WithMessage(err, "trusted clients")
+	}
+
+	r := &redactor{trusted: trusted}
+	for n, rc := range cfg.Rules {
+		rule := redactRule{
+			name:        rc.Name,
+			listeners:   rc.Listeners,
+			fields:      map[string]bool{},
+			replacement: rc.Replacement,
+		}
+		if rule.name == "" {
+			rule.name = "#" + strconv.Itoa(n+1)
+		}
+		if rule.replacement == "" {
+			rule.replacement = replacement
+		}
+
+		if rule.clients, err = parseCIDRs(rc.Clients); err != nil {
+			return nil, errors.WithMessagef(err, "redaction rule %s", rule.name)
+		}
+		if rule.patterns, err = compileRegexps(rc.Patterns); err != nil {
+			return nil, errors.WithMessagef(err, "redaction rule %s", rule.name)
+		}
+		for _, zone := range rc.Zones {
+			rule.zones = append(rule.zones, strings.ToLower(strings.Trim(zone, ".")))
+		}
+		for _, field := range rc.Fields {
+			rule.fields[strings.ToLower(strings.TrimSpace(field))] = true
+		}
+
+		r.rules = append(r.rules, rule)
+	}
+
+	return r, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "bad CIDR %q", cidr)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// apply - скрыть данные по всем подходящим правилам. Доверенным клиентам ответ выдается целиком.
+func (r *redactor) apply(cl client, fqdn, whoisInfo string) string {
+	if r == nil {
+		return whoisInfo
+	}
+
+	ip := clientIP(cl.remote)
+	if ip != nil && containsIP(r.trusted, ip) {
+		return whoisInfo
+	}
+
+	for _, rule := range r.rules {
+		if rule.match(cl, ip, fqdn) {
+			whoisInfo = rule.redact(whoisInfo)
+		}
+	}
+
+	return whoisInfo
+}
+
+// applies - к ответу для клиента будет применено хотя бы одно правило
+func (r *redactor) applies(cl client, fqdn string) bool {
+	if r == nil {
+		return false
+	}
+
+	ip := clientIP(cl.remote)
+	if ip != nil && containsIP(r.trusted, ip) {
+		return false
+	}
+
+	for _, rule := range r.rules {
+		if rule.match(cl, ip, fqdn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rule redactRule) match(cl client, ip net.IP, fqdn string) bool {
+	if len(rule.listeners) > 0 && !matchListener(rule.listeners, cl.listener) {
+		return false
+	}
+
+	if len(rule.clients) > 0 && (ip == nil || !containsIP(rule.clients, ip)) {
+		return false
+	}
+
+	if len(rule.zones) > 0 {
+		matched := false
+		for _, zone := range rule.zones {
+			if fqdn == zone || strings.HasSuffix(fqdn, "."+zone) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (rule redactRule) redact(whoisInfo string) string {
+	if len(rule.fields) > 0 {
+		lines := strings.Split(whoisInfo, "\n")
+		for i, line := range lines {
+			m := fieldLine.FindStringSubmatch(line)
+			if m != nil && rule.fields[strings.ToLower(m[2])] {
+				lines[i] = m[1] + m[2] + m[3] + rule.replacement + m[5]
+			}
+		}
+		whoisInfo = strings.Join(lines, "\n")
+	}
+
+	for _, re := range rule.patterns {
+		whoisInfo = re.ReplaceAllLiteralString(whoisInfo, rule.replacement)
+	}
+
+	return whoisInfo
+}
+
+// matchListener - "host:port" точное совпадение, ":port" - любой адрес с этим портом
+func matchListener(listeners []string, addr net.Addr) bool {
+	if addr == nil {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	for _, l := range listeners {
+		lHost, lPort, err := net.SplitHostPort(l)
+		if err != nil || lPort != port {
+			continue
+		}
+		if lHost == "" || lHost == host || net.ParseIP(lHost).Equal(net.ParseIP(host)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func clientIP(addr net.Addr) net.IP {
+	if addr == nil {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package whois
+
+import (
+	"net"
+	"testing"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func TestRedactor_Apply(t *testing.T) {
+	const raw = "domain:        EXAMPLE.RU\r\n" +
+		"person:        Ivan Ivanov\r\n" +
+		"phone:         +7 812 000 00 00\r\n" +
+		"e-mail:        ivan@example.ru\r\n" +
+		"registrar:     RU-CENTER-RU\r\n" +
+		"remarks:       contact admin@example.ru\r\n"
+
+	r, err := newRedactor(config.Redaction{
+		Trusted: []string{"10.0.0.0/8"},
+		Rules: []config.RedactionRule{
+			{
+				Name:   "ru persons",
+				Zones:  []string{"ru"},
+				Fields: []string{"Person", "phone", "e-mail"},
+			},
+			{
+				Name:        "emails for public listener",
+				Listeners:   []string{":43"},
+				Patterns:    []string{`[\w.+-]+@[\w-]+(\.[\w-]+)+`},
+				Replacement: "[email]",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("redactor not created: %v", err)
+	}
+
+	public := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 43}
+	internal := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4343}
+
+	testCases := []struct {
+		name     string
+		cl       client
+		fqdn     string
+		expected string
+	}{
+		{
+			name: "public listener",
+			cl:   client{listener: public, remote: &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 5000}},
+			fqdn: "example.ru",
+			expected: "domain:        EXAMPLE.RU\r\n" +
+				"person:        REDACTED FOR PRIVACY\r\n" +
+				"phone:         REDACTED FOR PRIVACY\r\n" +
+				"e-mail:        REDACTED FOR PRIVACY\r\n" +
+				"registrar:     RU-CENTER-RU\r\n" +
+				"remarks:       contact [email]\r\n",
+		},
+		{
+			name:     "other listener and zone",
+			cl:       client{listener: internal, remote: &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 5000}},
+			fqdn:     "example.com",
+			expected: raw,
+		},
+		{
+			name:     "trusted client",
+			cl:       client{listener: public, remote: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5000}},
+			fqdn:     "example.ru",
+			expected: raw,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := r.apply(test.cl, test.fqdn, raw); got != test.expected {
+			t.Errorf("unexpected result for %s:\n%q", test.name, got)
+		}
+	}
+
+	var disabled *redactor
+	if got := disabled.apply(client{}, "example.ru", raw); got != raw {
+		t.Errorf("disabled redactor changed answer")
+	}
+
+	for _, bad := range []config.Redaction{
+		{Trusted: []string{"10.0.0.0"}, Rules: []config.RedactionRule{{Fields: []string{"phone"}}}},
+		{Rules: []config.RedactionRule{{Clients: []string{"bad"}}}},
+		{Rules: []config.RedactionRule{{Patterns: []string{"("}}}},
+	} {
+		if _, err := newRedactor(bad); err == nil {
+			t.Errorf("no error for bad redaction config: %+v", bad)
+		}
+	}
+}
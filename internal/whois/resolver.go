@@ -0,0 +1,103 @@
+package whois
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/resolve"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+const (
+	ResolverStatic  = "static"  // domainZoneWhois
+	ResolverIANA    = "iana"    // поле "whois:" ответа whois.iana.org
+	ResolverDNS     = "dns"     // CNAME "<tld>.whois-servers.net"
+	ResolverRDAP    = "rdap"    // port43 ответа RDAP сервера зоны
+	ResolverDefault = "default" // defaultWhois
+)
+
+// defaultResolverChain - прежний выбор whois сервера: domainZoneWhois, затем defaultWhois
+var defaultResolverChain = []string{ResolverStatic, ResolverDefault}
+
+// newResolver - цепочка источников whois серверов из resolvers.chain
+func (w *ProxyWhoisServer) newResolver(cfg config.Resolvers) (*resolve.Chain, error) {
+	names := cfg.Chain
+	if len(names) == 0 {
+		names = defaultResolverChain
+	}
+
+	ttl := time.Duration(cfg.TTL) * time.Second
+	resolvers := make([]resolve.Resolver, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case ResolverStatic:
+			resolvers = append(resolvers, resolve.Static(w.cfg.DomainZoneWhois))
+		case ResolverIANA:
+			resolvers = append(resolvers, resolve.NewIANA(cfg.IANAServer, w.resolverQuery, ttl))
+		case ResolverDNS:
+			resolvers = append(resolvers, resolve.NewDNS(cfg.DNSSuffix, nil, ttl))
+		case ResolverRDAP:
+			resolvers = append(resolvers, resolve.NewRDAP(cfg.RDAPBootstrap, time.Duration(cfg.RDAPTimeout)*time.Millisecond, ttl))
+		case ResolverDefault:
+			resolvers = append(resolvers, resolve.Default(w.cfg.DefaultWhois))
+		default:
+			return nil, errors.Errorf("unknown resolver %q", name)
+		}
+	}
+
+	return resolve.NewChain(resolvers...), nil
+}
+
+// resolverQuery - запрос резолвера к whois серверу (IANA) с таймаутами whois сервера
+func (w *ProxyWhoisServer) resolverQuery(ctx context.Context, addr, query string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	return upstream.Exchange(ctx, w.dial, addr, query, w.upstreamTimeouts(host, port), nil, w.maxResponseSize())
+}
+
+// getWhoisServer - whois сервер регистрируемого домена (punycode): сервер первого резолвера цепочки, который его нашел.
+// Шаги цепочки - в debug логе, для отладки - ResolveTrace.
+func (w *ProxyWhoisServer) getWhoisServer(ctx context.Context, fqdn string) (string, string, error) {
+	if len(w.domainZones(fqdn)) == 0 {
+		return "", "", errors.WithMessagef(upstream.ErrUnsupportedTLD, "no zone in %q", fqdn)
+	}
+
+	trace := w.resolver.Trace(ctx, fqdn)
+	for _, step := range trace.Steps {
+		w.logger.Debugf("resolver %s for %s: target %+v, error: %q", step.Resolver, fqdn, step.Target, step.Error)
+	}
+
+	if trace.Target == nil {
+		if err := ctx.Err(); err != nil {
+			return "", "", errors.WithMessagef(err, "resolve whois server of %s", fqdn)
+		}
+		return "", "", errors.WithMessagef(upstream.ErrUnsupportedTLD, "%s: nothing found for %s", w.resolver.Name(), fqdn)
+	}
+
+	target := trace.Target
+	w.logger.Debugf("whois server of %s: %s (%s: %s)", fqdn, target.Addr(), target.Resolver, target.Reason)
+
+	return target.Host, target.Port, nil
+}
+
+// ResolveTrace - как цепочка resolvers выбирает whois сервер для запроса: шаги, сервер и причины
+func (w *ProxyWhoisServer) ResolveTrace(ctx context.Context, query string) (resolve.Trace, error) {
+	domain, err := w.queryDomain(query)
+	if err != nil {
+		return resolve.Trace{}, err
+	}
+
+	fqdn := w.registrable(domain)
+	if len(w.domainZones(fqdn)) == 0 {
+		return resolve.Trace{}, errors.WithMessagef(upstream.ErrUnsupportedTLD, "no zone in %q", fqdn)
+	}
+
+	return w.resolver.Trace(ctx, fqdn), nil
+}
@@ -0,0 +1,49 @@
+package whois
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+func TestResolveTrace(t *testing.T) {
+	cfg := newTestConfig(func(cfg *config.Service) {
+		cfg.CacheTTL = 1
+		cfg.DefaultWhois = "whois.myorderbox.com"
+		cfg.DomainZoneWhois = map[string]string{"ru": "whois.tcinet.ru:43"}
+	})
+
+	server, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+
+	trace, err := server.ResolveTrace(context.Background(), "www.Example.COM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Domain != "example.com" || len(trace.Steps) != 2 || trace.Steps[0].Error == "" {
+		t.Errorf("unexpected trace: %+v", trace)
+	}
+	if trace.Target == nil || trace.Target.Addr() != "whois.myorderbox.com:43" || trace.Target.Resolver != ResolverDefault {
+		t.Errorf("unexpected target: %+v", trace.Target)
+	}
+
+	cfg.Resolvers.Chain = []string{ResolverStatic}
+	server, err = NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	if _, _, err := server.getWhoisServer(context.Background(), "example.com"); upstream.ClassifyError(err) != upstream.ErrorUnsupportedTLD {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Resolvers.Chain = []string{ResolverStatic, "whoisxml"}
+	if _, err := NewWhoisProxyServer(cfg, &logrus.Logger{}); err == nil {
+		t.Error("unknown resolver accepted")
+	}
+}
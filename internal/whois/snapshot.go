@@ -0,0 +1,67 @@
+package whois
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// snapshotter - кэш, умеющий сохранять себя на диск (memory backend)
+type snapshotter interface {
+	SaveSnapshot(path string) error
+	LoadSnapshot(path string) (int, error)
+}
+
+// loadSnapshot - прогрев кэша из snapshot предыдущего запуска. Битый snapshot пропускается.
+func (w *ProxyWhoisServer) loadSnapshot() {
+	s, ok := w.cache.(snapshotter)
+	if !ok || w.cfg.CacheSnapshot.Path == "" {
+		return
+	}
+
+	loaded, err := s.LoadSnapshot(w.cfg.CacheSnapshot.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			w.logger.WithError(err).Warnf("skip cache snapshot %s", w.cfg.CacheSnapshot.Path)
+		}
+		return
+	}
+
+	w.logger.Infof("Loaded %d cache entries from snapshot %s", loaded, w.cfg.CacheSnapshot.Path)
+}
+
+func (w *ProxyWhoisServer) saveSnapshot() error {
+	s, ok := w.cache.(snapshotter)
+	if !ok || w.cfg.CacheSnapshot.Path == "" {
+		return nil
+	}
+
+	if err := s.SaveSnapshot(w.cfg.CacheSnapshot.Path); err != nil {
+		return errors.WithMessage(err, "can't save cache snapshot")
+	}
+
+	w.logger.Debugf("cache snapshot saved to %s", w.cfg.CacheSnapshot.Path)
+	return nil
+}
+
+// snapshotLoop - периодическое сохранение snapshot (на случай падения без graceful shutdown)
+func (w *ProxyWhoisServer) snapshotLoop() {
+	if w.cfg.CacheSnapshot.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(w.cfg.CacheSnapshot.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if err := w.saveSnapshot(); err != nil {
+				w.logger.WithError(err).Error("periodic cache snapshot problem")
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package whois
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+)
+
+func TestWhoisProxyServer_SnapshotWarmStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whois-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := newTestConfig(func(cfg *config.Service) {
+		cfg.Port = "50003"
+		cfg.CacheSnapshot = config.CacheSnapshot{Path: filepath.Join(dir, "cache.snapshot")}
+	})
+
+	server, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+
+	_ = server.cache.SetEntry("example.ru", storage.Entry{Raw: "whois info", Upstream: "whois.tcinet.ru:43"})
+
+	if err := server.Stop(); err != nil {
+		t.Fatalf("can't stop server: %v", err)
+	}
+
+	restarted, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	defer restarted.Stop()
+
+	entry, found, _ := restarted.cache.GetEntry("example.ru")
+	if !found || entry.Raw != "whois info" {
+		t.Errorf("cache not warmed from snapshot: %+v", entry)
+	}
+
+	// битый snapshot не мешает старту
+	_ = ioutil.WriteFile(cfg.CacheSnapshot.Path, []byte("garbage"), 0644)
+	broken, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created with corrupt snapshot. err: %v", err)
+	}
+	_ = broken.Stop()
+}
@@ -0,0 +1,73 @@
+package whois
+
+import (
+	"net"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+// responseStream - ответ клиенту TCP по мере чтения из whois сервера (запрос без пост-обработки ответа).
+// Ошибка записи клиенту не прерывает чтение из whois сервера: ответ все равно попадает в кэш.
+type responseStream struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	prefix  string // строки-комментарии перед ответом (IDN, сокращение до регистрируемого домена)
+	started bool
+	err     error
+}
+
+func (s *responseStream) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return len(p), nil
+	}
+
+	if !s.started {
+		s.started = true
+		if s.prefix != "" {
+			s.write([]byte(s.prefix))
+		}
+	}
+	s.write(p)
+
+	return len(p), nil
+}
+
+func (s *responseStream) write(p []byte) {
+	if s.err != nil {
+		return
+	}
+	if s.err = s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); s.err != nil {
+		return
+	}
+	_, s.err = s.conn.Write(p)
+}
+
+// Started - часть ответа уже отправлена клиенту
+func (s *responseStream) Started() bool {
+	return s != nil && s.started
+}
+
+// passThrough - ответ whois сервера выдается клиенту без изменений, его можно отдавать по мере чтения
+func (w *ProxyWhoisServer) passThrough(cl client, fqdn, upstream string) bool {
+	return !w.cfg.CacheAnnotation &&
+		!w.redactor.applies(cl, fqdn) &&
+		!w.rewriter.MayApply(fqdn, upstream) &&
+		len(w.descInfoLines(fqdn)) == 0
+}
+
+func (w *ProxyWhoisServer) maxResponseSize() int {
+	if w.cfg.MaxResponseSize > 0 {
+		return w.cfg.MaxResponseSize
+	}
+	return upstream.ResponseSizeDefault
+}
+
+// deadline - срок через timeout, 0 - без срока
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
@@ -0,0 +1,79 @@
+package whois
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func TestReadFromConnection_Overflow(t *testing.T) {
+	server, clientConn := net.Pipe()
+	defer server.Close()
+	go func() { _, _ = io.WriteString(server, strings.Repeat("a", 300)) }()
+
+	if _, err := readFromConnection(clientConn, 100, time.Second); err == nil {
+		t.Error("no error for buffer overflow")
+	}
+}
+
+func TestWhoisProxyServer_StreamAnswer(t *testing.T) {
+	first := "domain:        EXAMPLE.RU\r\n"
+	rest := strings.Repeat("remarks:       streamed line\r\n", 100)
+	next := make(chan struct{})
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.Port = "50150"
+		cfg.ReadTimeout = 2
+		cfg.DomainZoneWhois = map[string]string{"ru": "whois.test:43"}
+	})
+	w.dial = pipeDial(func(conn net.Conn, query string) {
+		_, _ = io.WriteString(conn, first)
+		<-next
+		_, _ = io.WriteString(conn, rest)
+	})
+	if err := w.Start(); err != nil {
+		t.Fatalf("error while server.Start. err: %v", err)
+	}
+	waitServing(t, w.server.Addr())
+	defer w.Stop()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:50150")
+	if err != nil {
+		t.Fatalf("whois proxy server unavailable. err: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "sub.example.ru\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// первая часть ответа доходит до клиента, пока whois сервер еще не закончил ответ
+	note := reducedNote("sub.example.ru", "example.ru")
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	head := make([]byte, len(note)+len(first))
+	if _, err := io.ReadFull(conn, head); err != nil || string(head) != note+first {
+		t.Fatalf("answer is not streamed: %q %v", head, err)
+	}
+
+	close(next)
+	tail, err := ioutil.ReadAll(conn)
+	if err != nil || string(tail) != rest+"\r\n" {
+		t.Errorf("unexpected answer tail: %v (%d bytes)", err, len(tail))
+	}
+
+	entry, found, _ := w.cache.GetEntry("example.ru")
+	if !found || entry.Raw != first+rest {
+		t.Errorf("streamed answer is not cached: %v %d", found, len(entry.Raw))
+	}
+
+	// из кэша - тот же ответ целиком
+	answer, err := Client("127.0.0.1", "50150", "sub.example.ru")
+	if err != nil || answer != note+first+rest+"\r\n" {
+		t.Errorf("unexpected cached answer: %v (%d bytes)", err, len(answer))
+	}
+}
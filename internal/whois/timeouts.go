@@ -0,0 +1,77 @@
+package whois
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+const (
+	UpstreamDialTimeoutDefault  = 30 * time.Second
+	UpstreamTotalTimeoutDefault = time.Minute
+)
+
+// upstreamTimeouts - таймауты запроса к whois серверу host:port: заданные для сервера ("host:port", затем "host"),
+// затем общие, затем по умолчанию (first-byte и idle - readTimeout)
+func (w *ProxyWhoisServer) upstreamTimeouts(host, port string) upstream.Timeouts {
+	t := upstream.Timeouts{
+		Dial:      UpstreamDialTimeoutDefault,
+		Write:     time.Duration(w.cfg.WriteTimeout) * time.Second,
+		FirstByte: time.Duration(w.cfg.ReadTimeout) * time.Second,
+		Idle:      time.Duration(w.cfg.ReadTimeout) * time.Second,
+		Total:     UpstreamTotalTimeoutDefault,
+	}
+
+	overrideTimeouts(&t, w.cfg.Timeouts.UpstreamTimeouts)
+	if u, ok := w.cfg.Timeouts.Upstreams[host]; ok {
+		overrideTimeouts(&t, u)
+	}
+	if u, ok := w.cfg.Timeouts.Upstreams[net.JoinHostPort(host, port)]; ok {
+		overrideTimeouts(&t, u)
+	}
+
+	return t
+}
+
+func overrideTimeouts(t *upstream.Timeouts, u config.UpstreamTimeouts) {
+	set := func(d *time.Duration, ms int) {
+		if ms > 0 {
+			*d = time.Duration(ms) * time.Millisecond
+		}
+	}
+	set(&t.Dial, u.Dial)
+	set(&t.FirstByte, u.FirstByte)
+	set(&t.Idle, u.Idle)
+	set(&t.Total, u.Total)
+}
+
+// requestContext - контекст ответа на запрос клиента: общий срок на все обращения к репликам и whois серверам
+func (w *ProxyWhoisServer) requestContext(cl client) (context.Context, context.CancelFunc) {
+	ctx := cl.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if w.cfg.Timeouts.Request > 0 {
+		return context.WithTimeout(ctx, time.Duration(w.cfg.Timeouts.Request)*time.Millisecond)
+	}
+	return context.WithCancel(ctx)
+}
+
+// remaining - таймаут, урезанный до оставшегося срока ctx. false - срок уже истек
+func remaining(ctx context.Context, timeout time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout, true
+	}
+	left := time.Until(deadline)
+	if left <= 0 {
+		return 0, false
+	}
+	if timeout <= 0 || left < timeout {
+		return left, true
+	}
+	return timeout, true
+}
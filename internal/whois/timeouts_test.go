@@ -0,0 +1,66 @@
+package whois
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+func TestWhoisProxyServer_upstreamTimeouts(t *testing.T) {
+	w := &ProxyWhoisServer{cfg: &config.Service{
+		ReadTimeout:  2,
+		WriteTimeout: 1,
+		Timeouts: config.Timeouts{
+			UpstreamTimeouts: config.UpstreamTimeouts{Dial: 5000},
+			Upstreams: map[string]config.UpstreamTimeouts{
+				"whois.tcinet.ru":    {Idle: 500, Total: 10000},
+				"whois.tcinet.ru:43": {Total: 20000},
+			},
+		},
+	}}
+
+	testCases := []struct {
+		host, port string
+		expected   upstream.Timeouts
+	}{
+		{"whois.verisign-grs.com", "43", upstream.Timeouts{Dial: 5 * time.Second, Write: time.Second, FirstByte: 2 * time.Second, Idle: 2 * time.Second, Total: UpstreamTotalTimeoutDefault}},
+		{"whois.tcinet.ru", "4343", upstream.Timeouts{Dial: 5 * time.Second, Write: time.Second, FirstByte: 2 * time.Second, Idle: 500 * time.Millisecond, Total: 10 * time.Second}},
+		{"whois.tcinet.ru", "43", upstream.Timeouts{Dial: 5 * time.Second, Write: time.Second, FirstByte: 2 * time.Second, Idle: 500 * time.Millisecond, Total: 20 * time.Second}},
+	}
+
+	for n, test := range testCases {
+		if timeouts := w.upstreamTimeouts(test.host, test.port); timeouts != test.expected {
+			t.Errorf("unexpected timeouts for test case #%d: %+v", n, timeouts)
+		}
+	}
+}
+
+func TestWhoisProxyServer_RequestDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.ReadTimeout = 5
+		cfg.DomainZoneWhois = map[string]string{"ru": "whois.test:43"}
+		cfg.Timeouts = config.Timeouts{Request: 100}
+	})
+	defer w.cache.Close()
+	w.dial = pipeDial(func(conn net.Conn, query string) {
+		<-release
+		_, _ = io.WriteString(conn, "late answer\r\n")
+	})
+
+	started := time.Now()
+	answer, err := w.answerQuery(client{requestID: "deadline"}, "example.ru")
+	if upstream.ClassifyError(err) != upstream.ErrorUpstreamTimeout || time.Since(started) > time.Second {
+		t.Errorf("unexpected error: %v (%s)", err, time.Since(started))
+	}
+	if !strings.HasPrefix(answer, "% Error:") || !strings.Contains(answer, "deadline") {
+		t.Errorf("unexpected answer: %q", answer)
+	}
+}
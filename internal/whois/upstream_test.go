@@ -0,0 +1,141 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+// fakeWhois - локальный whois сервер для тестов без внешней сети
+type fakeWhois struct {
+	l       net.Listener
+	answer  func(query string) string
+	mu      sync.Mutex
+	queries []string
+}
+
+func newFakeWhois(t *testing.T, answer func(query string) string) *fakeWhois {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+
+	f := &fakeWhois{l: l, answer: answer}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeWhois) serve(conn net.Conn) {
+	defer conn.Close()
+
+	query, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	query = strings.TrimSpace(query)
+
+	f.mu.Lock()
+	f.queries = append(f.queries, query)
+	f.mu.Unlock()
+
+	_, _ = io.WriteString(conn, f.answer(query))
+}
+
+func (f *fakeWhois) Addr() string { return f.l.Addr().String() }
+
+func (f *fakeWhois) Close() { _ = f.l.Close() }
+
+// pipeDial - соединение с whois сервером через net.Pipe для upstream.Exchange: serve отвечает на запрос query,
+// соединение закрывается после serve
+func pipeDial(serve func(conn net.Conn, query string)) upstream.DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			query, err := bufio.NewReader(server).ReadString('\n')
+			if err != nil {
+				return
+			}
+			serve(server, strings.TrimSpace(query))
+		}()
+		return client, nil
+	}
+}
+
+func (f *fakeWhois) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.queries...)
+}
+
+// newTestConfig - конфигурация тестового сервера без внешней сети: TCP сервер на свободном порту,
+// defaultWhois - закрытый порт. override меняет поля под тест.
+func newTestConfig(override func(cfg *config.Service)) *config.Service {
+	cfg := &config.Service{
+		Host:            "127.0.0.1",
+		Port:            "0",
+		MaxCntConnect:   4,
+		MaxLenBuffer:    4096,
+		ReadTimeout:     1,
+		WriteTimeout:    1,
+		CacheTTL:        300,
+		DefaultWhois:    "127.0.0.1:1",
+		DomainZoneWhois: map[string]string{},
+	}
+	if override != nil {
+		override(cfg)
+	}
+	return cfg
+}
+
+// newTestServer - ProxyWhoisServer с конфигурацией newTestConfig(override)
+func newTestServer(t *testing.T, override func(cfg *config.Service)) *ProxyWhoisServer {
+	t.Helper()
+
+	w, err := NewWhoisProxyServer(newTestConfig(override), &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	return w
+}
+
+// waitServing - ждать, пока воркеры пула server.Server начнут принимать соединения на addr:
+// соединения, пришедшие раньше, отклоняются как при занятом пуле
+func waitServing(t *testing.T, addr string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			continue
+		}
+		_, _ = io.WriteString(conn, "ping\r\n")
+		_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _ := conn.Read(make([]byte, 1))
+		_ = conn.Close()
+		if n > 0 {
+			return
+		}
+	}
+
+	t.Fatalf("%s is not serving", addr)
+}
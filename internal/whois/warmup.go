@@ -0,0 +1,132 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	WarmupRateDefault     = 5 // запросов в секунду
+	warmupProgressDefault = 100
+)
+
+type (
+	// WarmupReport - прогресс и итог прогрева кэша
+	WarmupReport struct {
+		Total    int               // обработано доменов
+		Cached   int               // уже были в кэше
+		Fetched  int               // получены от whois серверов
+		Failed   int               // ошибки
+		Failures map[string]string // domain -> error
+	}
+
+	// WarmupProgressFunc - вызывается каждые warmupProgressDefault доменов и по завершении
+	WarmupProgressFunc = func(report WarmupReport)
+)
+
+// WarmupFromFile - прогрев кэша списком доменов из файла ("-" - stdin)
+func (w *ProxyWhoisServer) WarmupFromFile(ctx context.Context, path string, rate int, progress WarmupProgressFunc) (WarmupReport, error) {
+	if path == "-" {
+		return w.Warmup(ctx, os.Stdin, rate, progress)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return WarmupReport{}, errors.WithMessagef(err, "can't open warmup list %s", path)
+	}
+	defer f.Close()
+
+	return w.Warmup(ctx, f, rate, progress)
+}
+
+// Warmup - прогрев кэша: по домену на строку (пустые строки и # комментарии пропускаются),
+// через обычный путь lookup, не более rate запросов в секунду
+func (w *ProxyWhoisServer) Warmup(ctx context.Context, r io.Reader, rate int, progress WarmupProgressFunc) (WarmupReport, error) {
+	if rate <= 0 {
+		rate = WarmupRateDefault
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	report := WarmupReport{Failures: map[string]string{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		domain := strings.TrimSpace(scanner.Text())
+		if domain == "" || strings.HasPrefix(domain, "#") {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-ticker.C:
+		}
+
+		report.Total++
+
+		res, err := w.lookup(domain)
+		if err == nil {
+			err = upstreamError(ResultClass(res.entry.Class), res.entry.Raw) // rate limit, пустой ответ
+		}
+		switch {
+		case err != nil:
+			report.Failed++
+			report.Failures[domain] = err.Error()
+			w.logger.WithError(err).Warnf("warmup of %s failed", domain)
+		case res.cached:
+			report.Cached++
+		default:
+			report.Fetched++
+		}
+
+		if progress != nil && report.Total%warmupProgressDefault == 0 {
+			progress(report)
+		}
+	}
+
+	if progress != nil {
+		progress(report)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return report, errors.WithMessage(err, "can't read warmup list")
+	}
+
+	return report, nil
+}
+
+// warmupAtStartup - фоновый прогрев при старте сервиса, не задерживает запуск listener
+func (w *ProxyWhoisServer) warmupAtStartup() {
+	if !w.cfg.Warmup.AtStartup || w.cfg.Warmup.File == "" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-w.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	report, err := w.WarmupFromFile(ctx, w.cfg.Warmup.File, w.cfg.Warmup.Rate, func(report WarmupReport) {
+		w.logger.Infof("cache warmup: processed %d, cached %d, fetched %d, failed %d",
+			report.Total, report.Cached, report.Fetched, report.Failed)
+	})
+	if err != nil {
+		w.logger.WithError(err).Error("cache warmup problem")
+		return
+	}
+
+	w.logger.Infof("cache warmup finished: %d domains, %d failed", report.Total, report.Failed)
+}
@@ -0,0 +1,58 @@
+package whois
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+)
+
+func TestWhoisProxyServer_Warmup(t *testing.T) {
+	upstream := newFakeWhois(t, func(query string) string {
+		if strings.HasPrefix(query, "limited") {
+			return "Query limit exceeded\r\n"
+		}
+		return "domain:        " + query + "\r\n"
+	})
+	defer upstream.Close()
+
+	server := newTestServer(t, func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+	})
+	defer server.Stop()
+
+	list := "# customer domains\nexample.ru\n\nокна.ru\nexample.ru\nexample.com\nlimited.ru\n"
+
+	var calls int
+	report, err := server.Warmup(context.Background(), strings.NewReader(list), 1000, func(WarmupReport) { calls++ })
+	if err != nil {
+		t.Fatalf("warmup error: %v", err)
+	}
+
+	if report.Total != 5 || report.Fetched != 2 || report.Cached != 1 || report.Failed != 2 || calls != 1 {
+		t.Errorf("unexpected warmup report: %+v calls: %d", report, calls)
+	}
+	if _, found := report.Failures["limited.ru"]; !found {
+		t.Errorf("error answer of limited.ru not reported as failure: %v", report.Failures)
+	}
+	if _, found := report.Failures["example.com"]; !found {
+		t.Errorf("failure of example.com not reported: %v", report.Failures)
+	}
+
+	if _, found, _ := server.cache.GetEntry("xn--80atjc.ru"); !found {
+		t.Errorf("cache not warmed for idn domain")
+	}
+}
+
+func TestWhoisProxyServer_Warmup_Cancel(t *testing.T) {
+	server := newTestServer(t, nil)
+	defer server.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := server.Warmup(ctx, strings.NewReader("example.com\n"), 1, nil); err == nil {
+		t.Errorf("no error for cancelled warmup")
+	}
+}
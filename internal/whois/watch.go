@@ -0,0 +1,120 @@
+package whois
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/watch"
+)
+
+const WatchIntervalDefault = time.Hour
+
+func (w *ProxyWhoisServer) newWatcher(cfg config.Watch) (*watch.Watcher, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+
+	if cfg.Webhook.URL == "" {
+		return nil, errors.New("watch webhook url is empty")
+	}
+
+	domains := append([]string{}, cfg.Domains...)
+	if cfg.File != "" {
+		fromFile, err := readDomainList(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, fromFile...)
+	}
+
+	webhook := watch.NewWebhook(cfg.Webhook.URL,
+		time.Duration(cfg.Webhook.Timeout)*time.Millisecond,
+		cfg.Webhook.Retries,
+		time.Duration(cfg.Webhook.Backoff)*time.Millisecond)
+
+	// через обычный путь lookup: кэш, выбор whois сервера, peers.
+	// Ответ-ошибка whois сервера возвращается ошибкой, чтобы домен был пропущен, а не "изменился".
+	lookup := func(domain string) (string, error) {
+		res, err := w.lookup(domain)
+		if err != nil {
+			return "", err
+		}
+		if err := upstreamError(ResultClass(res.entry.Class), res.entry.Raw); err != nil {
+			return "", err
+		}
+		return res.entry.Raw, nil
+	}
+
+	return watch.New(domains, lookup, webhook.Send, cfg.Thresholds, cfg.State)
+}
+
+// readDomainList - по домену на строку, пустые строки и # комментарии пропускаются
+func readDomainList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't open domain list %s", path)
+	}
+	defer f.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if domain := strings.TrimSpace(scanner.Text()); domain != "" && !strings.HasPrefix(domain, "#") {
+			domains = append(domains, domain)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithMessagef(err, "can't read domain list %s", path)
+	}
+
+	return domains, nil
+}
+
+// watchLoop - периодическая проверка отслеживаемых доменов
+func (w *ProxyWhoisServer) watchLoop() {
+	if w.watcher == nil {
+		return
+	}
+
+	ticker := time.NewTicker(durationOrDefault(w.cfg.Watch.Interval, WatchIntervalDefault))
+	defer ticker.Stop()
+
+	for {
+		w.watchRound()
+
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ProxyWhoisServer) watchRound() {
+	for _, domain := range w.watcher.Domains() {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		events, err := w.watcher.Check(domain)
+		if err != nil {
+			w.logger.WithError(err).Warnf("watch of %s failed", domain)
+			continue
+		}
+		for _, ev := range events {
+			w.logger.Infof("watch: %s event for %s sent", ev.Type, ev.Domain)
+		}
+	}
+
+	if err := w.watcher.Save(); err != nil {
+		w.logger.WithError(err).Error("watch state problem")
+	}
+}
@@ -0,0 +1,107 @@
+package whois
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/watch"
+)
+
+func TestWhoisProxyServer_Watch(t *testing.T) {
+	var nserver atomic.Value
+	nserver.Store("ns1.example.ru")
+	var limited atomic.Value
+	limited.Store(false)
+	upstream := newFakeWhois(t, func(query string) string {
+		if limited.Load().(bool) {
+			return "Query limit exceeded\r\n"
+		}
+		return "domain:        " + query + "\r\nnserver:       " + nserver.Load().(string) + ".\r\n" +
+			"registrar:     RU-CENTER-RU\r\npaid-till:     2099-01-01T00:00:00Z\r\n"
+	})
+	defer upstream.Close()
+
+	events := make(chan watch.Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var ev watch.Event
+		_ = json.NewDecoder(r.Body).Decode(&ev)
+		events <- ev
+	}))
+	defer receiver.Close()
+
+	dir, err := ioutil.TempDir("", "whois-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := filepath.Join(dir, "domains.txt")
+	_ = ioutil.WriteFile(list, []byte("# customers\nexample.ru\n\n"), 0644)
+
+	cfg := newTestConfig(func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{"ru": upstream.Addr()}
+		cfg.Watch = config.Watch{
+			Enable:  true,
+			File:    list,
+			State:   filepath.Join(dir, "state.json"),
+			Webhook: config.Webhook{URL: receiver.URL, Timeout: 1000},
+		}
+	})
+
+	w, err := NewWhoisProxyServer(cfg, &logrus.Logger{})
+	if err != nil {
+		t.Fatalf("proxy server whois not created. err: %v", err)
+	}
+	defer w.cache.Close()
+
+	w.watchRound()
+
+	nserver.Store("ns2.example.ru")
+	_ = w.cache.Remove("example.ru")
+	w.watchRound()
+
+	select {
+	case ev := <-events:
+		if ev.Type != watch.EventChanged || ev.Domain != "example.ru" || len(ev.Changes) != 1 ||
+			ev.Changes[0].Field != "nameservers" || ev.Changes[0].New != "ns2.example.ru" {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	default:
+		t.Fatalf("change event not sent")
+	}
+
+	if len(events) != 0 {
+		t.Errorf("unexpected extra events: %d", len(events))
+	}
+
+	// ответ-ошибка upstream не считается изменением и не сбрасывает состояние домена
+	limited.Store(true)
+	_ = w.cache.Remove("example.ru")
+	w.watchRound()
+
+	limited.Store(false)
+	_ = w.cache.Remove("example.ru")
+	w.watchRound()
+
+	if len(events) != 0 {
+		t.Errorf("unexpected events for upstream error answer: %+v", <-events)
+	}
+
+	if _, err := os.Stat(cfg.Watch.State); err != nil {
+		t.Errorf("watch state not saved: %v", err)
+	}
+
+	cfg.Watch.Webhook.URL = ""
+	if _, err := NewWhoisProxyServer(cfg, &logrus.Logger{}); err == nil {
+		t.Errorf("watcher without webhook created")
+	}
+}
@@ -1,34 +1,78 @@
 package whois
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
-	"regexp"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
-	"golang.org/x/net/idna"
-
 	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/descinfo"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/history"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/psl"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/resolve"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/rewrite"
 	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/server"
 	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/storage"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/watch"
 )
 
 // it's dirty pkg - I know =/ , but it is logical to test
 
 type (
+	// lookupResult - результат lookup: итоговый запрос к whois серверу и сырой ответ
+	lookupResult struct {
+		query  string // домен запроса (punycode) до сокращения до регистрируемого домена
+		fqdn   string
+		entry  storage.Entry
+		cached bool
+	}
+
+	// Answer - ответ на запрос домена: текст для клиента (после пост-обработки) и сырой ответ whois сервера
+	Answer struct {
+		Query     string
+		Domain    string // регистрируемый домен (punycode), для которого запрошен whois
+		Upstream  string // host:port whois сервера
+		Class     ResultClass
+		Raw       string
+		Text      string // пусто, если ответ уже отдан клиенту TCP по мере чтения
+		FetchedAt time.Time
+		Cached    bool
+	}
+
 	ProxyWhoisServer struct {
 		server *server.Server
 		cfg    *config.Service
 		logger *logrus.Logger
-		cache  *storage.WhoisDataStorage
-
-		defaultWhoisHost string
-		defaultWhoisPort string
+		cache  storage.Cache
+
+		classifier *classifier
+		peers      *peerSet
+		suffixes   *psl.Source
+		idna       *idnaProfiles
+		resolver   *resolve.Chain
+
+		invalidation *invalidation
+		history      *history.Store
+		watcher      *watch.Watcher
+		redactor     *redactor
+		rewriter     *rewrite.Engine
+		descInfo     *descinfo.Source
+		httpServer   *http.Server
+
+		errorTemplates map[upstream.ErrorClass]string
+		dial           upstream.DialFunc // соединение с whois серверами, nil - net.Dialer
+
+		stop     chan struct{}
+		stopOnce sync.Once
 	}
 )
 
@@ -38,14 +82,90 @@ func NewWhoisProxyServer(cfg *config.Service, logger *logrus.Logger) (*ProxyWhoi
 		return nil, errors.WithMessagef(err, "can't create new tcp server")
 	}
 
-	return &ProxyWhoisServer{
-		server:           tcpServer,
-		cfg:              cfg,
-		logger:           logger,
-		cache:            storage.New(time.Duration(cfg.CacheTTL)*time.Second, time.Duration(cfg.CacheReset)*time.Second),
-		defaultWhoisHost: strings.Split(cfg.DefaultWhois, ":")[0],
-		defaultWhoisPort: strings.Split(cfg.DefaultWhois, ":")[1],
-	}, nil
+	classifier, err := newClassifier(cfg.ResultPatterns)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't compile result patterns")
+	}
+
+	cache, err := newCache(cfg)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't create cache")
+	}
+
+	peers, err := newPeers(cfg.Peers, cfg.MaxCntConnect)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't create peers")
+	}
+
+	invalidation, err := newInvalidation(cfg)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't create cache invalidation")
+	}
+
+	redactor, err := newRedactor(cfg.Redaction)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't compile redaction rules")
+	}
+
+	rewriter, err := newRewriter(cfg.AddWhoisDescInfo, cfg.Rewrite)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't compile rewrite rules")
+	}
+
+	descInfo, err := newDescInfoSource(cfg.DescInfoSource)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't load desc info source")
+	}
+
+	suffixes, err := newPublicSuffix(cfg.PublicSuffix)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't load public suffix list")
+	}
+
+	idnaProfiles, err := newIDNAProfiles(cfg.IDNA)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't create idna profiles")
+	}
+
+	errorTemplates, err := newErrorTemplates(cfg.ErrorTemplates)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't load error templates")
+	}
+
+	historyStore, err := newHistory(cfg.History)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "can't open history")
+	}
+
+	w := &ProxyWhoisServer{
+		server:         tcpServer,
+		cfg:            cfg,
+		logger:         logger,
+		cache:          cache,
+		classifier:     classifier,
+		peers:          peers,
+		suffixes:       suffixes,
+		idna:           idnaProfiles,
+		invalidation:   invalidation,
+		history:        historyStore,
+		redactor:       redactor,
+		rewriter:       rewriter,
+		descInfo:       descInfo,
+		errorTemplates: errorTemplates,
+		stop:           make(chan struct{}),
+	}
+
+	if w.resolver, err = w.newResolver(cfg.Resolvers); err != nil {
+		return nil, errors.WithMessagef(err, "can't create resolvers")
+	}
+
+	if w.watcher, err = w.newWatcher(cfg.Watch); err != nil {
+		return nil, errors.WithMessagef(err, "can't create watcher")
+	}
+
+	w.loadSnapshot()
+
+	return w, nil
 }
 
 func (w *ProxyWhoisServer) Start() error {
@@ -63,9 +183,67 @@ func (w *ProxyWhoisServer) Start() error {
 	}
 	w.logger.Infof("Whois Proxy Server starts at %s", w.server.Addr())
 
+	if err := w.startPeers(chErr); err != nil {
+		return err
+	}
+
+	if err := w.startHTTP(); err != nil {
+		return err
+	}
+
+	if err := w.startInvalidation(); err != nil {
+		return err
+	}
+
+	go w.snapshotLoop()
+	go w.prefetchLoop()
+	go w.warmupAtStartup()
+	go w.historyLoop()
+	go w.watchLoop()
+	go w.descInfoLoop()
+	go w.publicSuffixLoop()
+
 	return nil
 }
 
+// CacheStats - счетчики и размер кэша whois ответов
+func (w *ProxyWhoisServer) CacheStats() storage.Stats {
+	return w.cache.Stats()
+}
+
+// Stop - graceful shutdown: закрыть listener, сохранить snapshot кэша, закрыть кэш
+func (w *ProxyWhoisServer) Stop() error {
+	var err error
+	w.stopOnce.Do(func() {
+		close(w.stop)
+
+		if e := w.server.Close(); e != nil {
+			err = errors.WithMessage(e, "can't close tcp server")
+		}
+
+		if w.peers != nil {
+			if e := w.peers.server.Close(); e != nil {
+				err = errors.WithMessage(e, "can't close peers server")
+			}
+		}
+
+		if w.httpServer != nil {
+			if e := w.httpServer.Close(); e != nil {
+				err = errors.WithMessage(e, "can't close http api server")
+			}
+		}
+
+		if e := w.saveSnapshot(); e != nil {
+			err = e
+		}
+
+		if e := w.cache.Close(); e != nil {
+			err = errors.WithMessage(e, "can't close cache")
+		}
+	})
+	return err
+}
+
 func (w *ProxyWhoisServer) TCPHandler(conn net.Conn) (err error) {
 	var (
 		request  string
@@ -91,73 +269,168 @@ func (w *ProxyWhoisServer) TCPHandler(conn net.Conn) (err error) {
 		return nil // disable this error, because it's raise by TCP health-check usually
 	}
 
-	response, err = w.processRequest(request)
+	cl := client{listener: conn.LocalAddr(), remote: conn.RemoteAddr(), requestID: newRequestID()}
+
+	if lines := requestLines(request); isBatchRequest(lines) {
+		return w.tcpBatch(conn, cl, lines)
+	}
+
+	stream := &responseStream{conn: conn, timeout: time.Duration(w.cfg.WriteTimeout) * time.Second}
+	cl.stream = stream
+
+	// при ошибке response - ответ "% Error: ..." по классу ошибки, сама ошибка уже в логе.
+	// Если ответ whois сервера уже отдается клиенту по мере чтения, response - только ошибка после него.
+	response, _ = w.processRequest(cl, request)
+	if stream.Started() && response != "" {
+		response = "\r\n" + response
+	}
 
 	err = writeToConnection(conn, time.Duration(w.cfg.WriteTimeout)*time.Second, response)
+	if stream.err != nil {
+		return errors.WithMessage(stream.err, "error while streaming whois answer")
+	}
 
 	return err
 }
 
-func (w *ProxyWhoisServer) processRequest(request string) (string, error) {
+func (w *ProxyWhoisServer) processRequest(cl client, request string) (string, error) {
 	w.logger.Debugf("Request: %s", request)
-	fqdn := strings.Split(request, "\r\n")[0]
 
-	// TODO подумать над этим местом, по-хорошему проверка нужна
-	// err := isValidHostname()
-	// if err != nil {
-	//	 w.logger.Warningf("Hostname not valid (regexp): %s", fqdn)
-	//	 return fmt.Sprintf(w.cfg.ErrorMsgTemplate, fqdn), nil // think about response
-	// }
+	answer, err := w.answerQuery(cl, strings.Split(request, "\r\n")[0])
+	if errors.Cause(err) == upstream.ErrInvalidQuery {
+		return answer, nil
+	}
+
+	return answer, err
+}
 
-	fqdn, err := convertToPunycode(fqdn)
+// answerQuery - ответ на одну строку запроса. При ошибке возвращается ответ по шаблону
+// класса ошибки (см. upstream.ClassifyError) вместе с самой ошибкой.
+func (w *ProxyWhoisServer) answerQuery(cl client, query string) (string, error) {
+	ctx, cancel := w.requestContext(cl)
+	defer cancel()
+
+	if domain, ok := splitAvailabilityQuery(query); ok {
+		answer, err := w.availabilityAnswer(ctx, domain)
+		if err != nil {
+			return w.errorAnswer(cl, domain, err), err
+		}
+		return answer, nil
+	}
+
+	if domain, at, ok := splitHistoryQuery(query); ok && w.history != nil {
+		answer, err := w.historyAnswer(cl, domain, at)
+		if err != nil {
+			return w.errorAnswer(cl, query, err), err
+		}
+		return answer, nil
+	}
+
+	answer, err := w.lookupAnswer(ctx, cl, query)
 	if err != nil {
-		w.logger.Warningf("Hostname not valid (idna: ToASCII): %s", fqdn)
-		return fmt.Sprintf(w.cfg.ErrorMsgTemplate, fqdn), nil // think about response
+		return w.errorAnswer(cl, query, err), err
 	}
 
-	// determining which server will apply for who who info
-	whoisHost, whoisPort, err := w.getWhoisServer(fqdn)
+	return answer.Text, nil
+}
+
+// Lookup - ответ на запрос домена для встраивания (pkg/proxy): тот же путь, что у клиентов TCP, без шаблонов ошибок.
+// Класс ошибки - upstream.ClassifyError.
+func (w *ProxyWhoisServer) Lookup(ctx context.Context, query string) (Answer, error) {
+	cl := client{ctx: ctx, requestID: newRequestID()}
+
+	ctx, cancel := w.requestContext(cl)
+	defer cancel()
+
+	return w.lookupAnswer(ctx, cl, query)
+}
+
+// lookupAnswer - lookup, проверка ответа whois сервера и пост-обработка для клиента
+func (w *ProxyWhoisServer) lookupAnswer(ctx context.Context, cl client, query string) (Answer, error) {
+	res, err := w.lookupFor(ctx, cl, query)
+	if err == nil {
+		err = upstreamError(ResultClass(res.entry.Class), res.entry.Raw)
+	}
 	if err != nil {
-		return "", errors.WithMessagef(err, "error while getWhoisServer()")
+		return Answer{Query: query}, err
 	}
-	w.logger.Debugf("whoisServer: %s:%s", whoisHost, whoisPort)
 
-	// get whois info (from cache or make request to whoisServer)
-	whoisInfo, err := w.getWhoisInfoCached(fqdn, whoisHost, whoisPort)
+	answer := Answer{
+		Query:     query,
+		Domain:    res.fqdn,
+		Upstream:  res.entry.Upstream,
+		Class:     ResultClass(res.entry.Class),
+		Raw:       res.entry.Raw,
+		FetchedAt: res.entry.FetchedAt,
+		Cached:    res.cached,
+	}
+
+	if cl.stream.Started() { // ответ уже отдан клиенту по мере чтения
+		return answer, nil
+	}
+
+	// custom fields etc. are applied on every read, so config changes take effect immediately
+	text, err := w.postProcess(cl, res.fqdn, res.entry)
 	if err != nil {
-		return "", err
+		return answer, err
 	}
+	answer.Text = w.answerPrefix(query, res) + text
 
-	return whoisInfo, nil
+	return answer, nil
 }
 
-func convertToPunycode(fqdn string) (string, error) {
-	p := idna.New(
-		idna.MapForLookup(),
-		idna.Transitional(true),
-		idna.StrictDomainName(true))
+// answerPrefix - строки-комментарии перед ответом: IDN запрос, сокращение до регистрируемого домена
+func (w *ProxyWhoisServer) answerPrefix(query string, res lookupResult) string {
+	prefix := w.idna.idnaNote(query, res.fqdn)
+	if res.query != res.fqdn {
+		prefix += reducedNote(res.query, res.fqdn)
+	}
+	return prefix
+}
 
-	// convert to idna.Punycode
-	return p.ToASCII(fqdn)
+// lookup - преобразование запроса, выбор whois сервера и сырой ответ (из кэша или запрос к whois серверу).
+// Общий путь для клиентских запросов, прогрева кэша и т.д.
+func (w *ProxyWhoisServer) lookup(query string) (lookupResult, error) {
+	return w.lookupFor(context.Background(), client{}, query)
 }
 
-//nolint:gocritic
-func (w *ProxyWhoisServer) getWhoisServer(fqdn string) (string, string, error) {
-	domainZones := getPossibleDomainZone(fqdn)
-	w.logger.Debug("domainZones:", domainZones)
-	if len(domainZones) == 0 {
-		return "", "", errors.New("len(domainZones) == 0")
+// lookupFor - lookup для клиента: при промахе кэша ответ без пост-обработки отдается в cl.stream по мере чтения.
+// ctx - срок ответа клиенту на все обращения к репликам и whois серверам.
+func (w *ProxyWhoisServer) lookupFor(ctx context.Context, cl client, query string) (lookupResult, error) {
+	var (
+		res lookupResult
+		err error
+	)
+
+	res.query, err = w.queryDomain(query)
+	if err != nil {
+		return res, err
 	}
+	res.fqdn = w.registrable(res.query)
 
-	for _, zone := range domainZones {
-		if addr, found := w.cfg.DomainZoneWhois[zone]; found {
-			return strings.Split(addr, ":")[0], strings.Split(addr, ":")[1], nil
-		}
+	// determining which server will apply for who who info
+	whoisHost, whoisPort, err := w.getWhoisServer(ctx, res.fqdn)
+	if err != nil {
+		return res, errors.WithMessagef(err, "error while getWhoisServer()")
+	}
+	w.logger.Debugf("whoisServer: %s:%s", whoisHost, whoisPort)
+
+	var dst io.Writer
+	if cl.stream != nil && w.passThrough(cl, res.fqdn, net.JoinHostPort(whoisHost, whoisPort)) {
+		cl.stream.prefix = w.answerPrefix(query, res)
+		dst = cl.stream
+	}
+
+	// get raw whois info (from cache or make request to whoisServer)
+	res.entry, res.cached, err = w.getWhoisInfoCached(ctx, res.fqdn, whoisHost, whoisPort, dst)
+	if err != nil {
+		return res, err
 	}
 
-	return w.defaultWhoisHost, w.defaultWhoisPort, nil
+	return res, nil
 }
 
+// getPossibleDomainZone - зоны по меткам, без учета публичных суффиксов (см. domainZones)
 // https://play.golang.org/p/BPYT1SZN1cA
 // super.site.beget.ru --> [site.beget.ru  beget.ru  ru]
 func getPossibleDomainZone(fqdn string) []string {
@@ -176,88 +449,58 @@ func recursiveAdd(s []string) (r []string) {
 	return
 }
 
-//nolint:unused,deadcode
-func isValidHostname(fqdn string) bool {
-	l := len(fqdn)
-	if l > 254 {
-		return false
-	}
-
-	if strings.HasSuffix(fqdn, ".") {
-		fqdn = fqdn[:l-1] //  # strip exactly one dot from the right, if present
-	}
-
-	labels := strings.Split(fqdn, ".")
-
-	r := regexp.MustCompile(`[0-9]+$`) // the TLD must be not all-numeric
-	if r.MatchString(labels[len(labels)-1]) {
-		return false
-	}
-
-	// Golang does not support Perl syntax ((?
-	// will throw out :
-	// error parsing regexp: invalid or unsupported Perl syntax: `(?!`
-	// patternStr := "^((?!-)[A-Za-z0-9-]{1,63}(?<!-)\\.)+[A-Za-z]{2,6}$"
-	// use regular expression without Perl syntax
-	//nolint:lll
-	domainRegExp := regexp.MustCompile(`^(([a-zA-Z]{1})|([a-zA-Z]{1}[a-zA-Z]{1})|([a-zA-Z]{1}[0-9]{1})|([0-9]{1}[a-zA-Z]{1})|([a-zA-Z0-9][a-zA-Z0-9-_]{1,61}[a-zA-Z0-9]))\.([a-zA-Z]{2,6}|[a-zA-Z0-9-]{2,30}\.[a-zA-Z]{2,3})$`)
-	for _, l := range labels {
-		if !domainRegExp.MatchString(l) {
-			return false
-		}
-	}
-	return true
-}
-
-func (w *ProxyWhoisServer) getWhoisInfo(fqdn, server, port string) (string, error) {
-	return w.whoisRequest(fqdn, server, port)
+func (w *ProxyWhoisServer) getWhoisInfo(ctx context.Context, fqdn, server, port string, dst io.Writer) (string, error) {
+	return w.whoisRequest(ctx, fqdn, server, port, dst)
 }
 
-func (w *ProxyWhoisServer) getWhoisInfoCached(fqdn, server, port string) (string, error) {
-	var err error
-
-	whoisInfo, found := w.cache.Get(fqdn)
+// getWhoisInfoCached - сырой ответ whois сервера с метаданными (из кэша или запрос к whois серверу).
+// dst - копия ответа whois сервера по мере чтения (только при запросе к whois серверу)
+func (w *ProxyWhoisServer) getWhoisInfoCached(ctx context.Context, fqdn, server, port string, dst io.Writer) (storage.Entry, bool, error) {
+	entry, found, err := w.cache.GetEntry(fqdn)
+	if err != nil { // недоступный кэш не должен ломать whois - идем напрямую
+		w.logger.WithError(err).Warn("cache get problem")
+	}
 	w.logger.Debugf("found from cache: %v", found)
+
 	if !found {
-		whoisInfo, err = w.getWhoisInfo(fqdn, server, port)
-		if err != nil {
-			return "", errors.WithMessagef(err, "error while getWhoisInfo()")
+		if entry, ok := w.fetchFromOwner(ctx, fqdn); ok {
+			return entry, false, nil
 		}
 
-		// Add Beget custom fields for whois
-		if addInfo, found := w.cfg.AddWhoisDescInfo[fqdn]; found {
-			whoisInfo, err = addCustomWhoisInfo(whoisInfo, addInfo)
-			if err != nil {
-				return "", errors.WithMessagef(err, "error while addCustomWhoisInfo()")
-			}
-		}
-		w.cache.Set(fqdn, whoisInfo)
+		entry, err = w.fetchAndCache(ctx, fqdn, server, port, dst)
+		return entry, false, err
 	}
 
-	return whoisInfo, err
+	return entry, true, nil
 }
 
-func addCustomWhoisInfo(originWhoisText string, customInfo []string) (string, error) {
-	var modifyWhoisText strings.Builder
-	for _, line := range strings.Split(originWhoisText, "\n") {
-		_, err := fmt.Fprintf(&modifyWhoisText, "%s\n", line)
-		if err != nil {
-			return "", err
-		}
+// fetchAndCache - запрос к whois серверу, классификация ответа и сохранение в кэш
+func (w *ProxyWhoisServer) fetchAndCache(ctx context.Context, fqdn, server, port string, dst io.Writer) (storage.Entry, error) {
+	whoisInfo, err := w.getWhoisInfo(ctx, fqdn, server, port, dst)
+	if err != nil {
+		return storage.Entry{}, errors.WithMessagef(err, "error while getWhoisInfo()")
+	}
 
-		if strings.HasPrefix(line, "source:") {
-			for _, v := range customInfo {
-				_, err := fmt.Fprintf(&modifyWhoisText, "%s\n", v)
-				if err != nil {
-					return "", err
-				}
-			}
-		}
+	class := w.classifier.classify(server, whoisInfo)
+	entry := storage.Entry{
+		Raw:       whoisInfo,
+		FetchedAt: time.Now(),
+		TTL:       w.jitterTTL(w.cacheTTL(fqdn, class)),
+		Upstream:  net.JoinHostPort(server, port),
+		Class:     string(class),
 	}
+	w.logger.Debugf("whois answer class: %s, cache ttl: %s", class, entry.TTL)
 
-	return modifyWhoisText.String(), nil
+	if err := w.cache.SetEntry(fqdn, entry); err != nil {
+		w.logger.WithError(err).Warn("cache set problem")
+	}
+
+	w.recordHistory(fqdn, class, entry.Raw, entry.Upstream, entry.FetchedAt)
+
+	return entry, nil
 }
 
+// readFromConnection - запрос клиента: до "\r\n" в конце прочитанного, EOF или таймаута, не больше maxLenBuf байт
 func readFromConnection(conn net.Conn, maxLenBuf int, timeout time.Duration) (string, error) {
 	err := conn.SetReadDeadline(time.Now().Add(timeout))
 	if err != nil {
@@ -277,7 +520,7 @@ func readFromConnection(conn net.Conn, maxLenBuf int, timeout time.Duration) (st
 
 		// защита от флуда
 		if len(buf)+n > maxLenBuf {
-			return "", errors.WithMessage(err, "buffer for read - overflow")
+			return "", errors.New("buffer for read - overflow")
 		}
 
 		buf = append(buf, tmp[:n]...)
@@ -291,7 +534,7 @@ func readFromConnection(conn net.Conn, maxLenBuf int, timeout time.Duration) (st
 }
 
 func writeToConnection(conn net.Conn, timeout time.Duration, s string) error {
-	err := conn.SetWriteDeadline(time.Now().Add(timeout))
+	err := conn.SetWriteDeadline(deadline(timeout))
 	if err != nil {
 		return nil
 	}
@@ -299,13 +542,13 @@ func writeToConnection(conn net.Conn, timeout time.Duration, s string) error {
 	return errors.WithMessagef(err, "error while writeToConnection()")
 }
 
-func (w *ProxyWhoisServer) whoisRequest(fqdn, host, port string) (string, error) {
+func (w *ProxyWhoisServer) whoisRequest(ctx context.Context, fqdn, host, port string, dst io.Writer) (string, error) {
 	fqdn = strings.Trim(strings.TrimSpace(fqdn), ".")
 	if fqdn == "" {
 		return "", fmt.Errorf("domain is empty")
 	}
 
-	result, err := w.query(fqdn, host, port)
+	result, err := w.query(ctx, fqdn, host, port, dst)
 	if err != nil {
 		return "", err
 	}
@@ -313,27 +556,6 @@ func (w *ProxyWhoisServer) whoisRequest(fqdn, host, port string) (string, error)
 	return result, nil
 }
 
-func (w *ProxyWhoisServer) query(domain, host, port string) (result string, err error) {
-	var conn net.Conn
-	conn, err = net.DialTimeout("tcp", net.JoinHostPort(host, port), time.Second*30)
-	if err != nil {
-		return "", err
-	}
-
-	defer func() {
-		if err != nil { // чтобы не затирать входящую ошибку
-			_ = conn.Close()
-		} else {
-			err = conn.Close()
-		}
-	}()
-
-	err = writeToConnection(conn, time.Duration(w.cfg.WriteTimeout)*time.Second, fmt.Sprintf("%s\r\n", domain))
-	if err != nil {
-		return "", err
-	}
-
-	result, err = readFromConnection(conn, w.cfg.MaxLenBuffer, time.Duration(w.cfg.ReadTimeout)*time.Second)
-
-	return result, err
+func (w *ProxyWhoisServer) query(ctx context.Context, domain, host, port string, dst io.Writer) (string, error) {
+	return upstream.Exchange(ctx, w.dial, net.JoinHostPort(host, port), domain, w.upstreamTimeouts(host, port), dst, w.maxResponseSize())
 }
@@ -2,6 +2,11 @@ package whois
 
 import (
 	"testing"
+
+	"github.com/pkg/errors"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
 )
 
 // go test -covermode=count -coverprofile=coverage.cov && go tool cover -html=coverage.cov
@@ -31,29 +36,117 @@ func Test_getPossibleDomainZone(t *testing.T) {
 	}
 }
 
-func Test_convertToPunycode(t *testing.T) {
+func TestWhoisProxyServer_queryDomain(t *testing.T) {
+	w := &ProxyWhoisServer{cfg: &config.Service{}}
+
 	testCases := []struct {
-		domain string
-		err    error
+		query  string
 		result string
+		valid  bool
+	}{
+		{"example.com", "example.com", true},
+		{"https://www.Example.com:443/path?q=1", "example.com", true},
+		{"admin@окна.рф", "xn--80atjc.xn--p1ai", true},
+		{"example.ru.", "example.ru", true},
+		{"example.photography", "example.photography", true},
+		{"bad_domain.ru", "", false},
+		{"-bad.ru", "", false},
+		{"example.123", "", false},
+		{"", "", false},
+	}
+
+	for n, test := range testCases {
+		fqdn, err := w.queryDomain(test.query)
+		if test.valid != (err == nil) || fqdn != test.result {
+			t.Errorf("unxpected result for test case #%d: %q %v", n, fqdn, err)
+		}
+		if err != nil && errors.Cause(err) != upstream.ErrInvalidQuery {
+			t.Errorf("unxpected error for test case #%d: %v", n, err)
+		}
+	}
+
+	w.cfg.QueryValidation = config.QueryValidation{AllowUnderscore: true, AllowLooseHyphens: true}
+	for _, query := range []string{"bad_domain.ru", "-bad.ru"} {
+		if _, err := w.queryDomain(query); err != nil {
+			t.Errorf("unxpected error for %s: %v", query, err)
+		}
+	}
+}
+
+func Test_splitHistoryQuery(t *testing.T) {
+	testCases := []struct {
+		query   string
+		history bool
 	}{
-		{"example.com", nil, "example.com"},
-		{"test.example.com", nil, "test.example.com"},
-		{"test.test.example.com", nil, "test.test.example.com"},
-		{"окна.рф", nil, "xn--80atjc.xn--p1ai"},
-		{"a.bc", nil, "a.bc"},
-		{"xn--kxae4bafwg.xn--pxaix.gr", nil, "xn--kxae4bafwg.xn--pxaix.gr"},
-		{"subdomain.subdomainsubdomainsuèdomainsubdomainsubdomainsubdomainsubdomain.net", nil,
-			"subdomain.xn--subdomainsubdomainsudomainsubdomainsubdomainsubdomainsubdomain-1mf.net"},
+		{"example.ru@2026-09-01", true},
+		{"example.ru@2026-09-01T10:00:00.5Z", true},
+		{"example.ru@yesterday", true},
+		{"admin@example.ru", false},
+		{"example.ru", false},
 	}
 
 	for n, test := range testCases {
-		puny, err := convertToPunycode(test.domain)
-		if err != test.err {
-			t.Errorf("unxpected error status: %v <> %v", err, test.err)
+		if _, _, ok := splitHistoryQuery(test.query); ok != test.history {
+			t.Errorf("unxpected result for test case #%d: %s", n, test.query)
+		}
+	}
+}
+
+func TestWhoisProxyServer_registrableLookup(t *testing.T) {
+	registry := newFakeWhois(t, func(query string) string { return "domain: " + query + "\r\n" })
+	defer registry.Close()
+	ru := newFakeWhois(t, func(query string) string { return "domain: " + query + "\r\n" })
+	defer ru.Close()
+
+	platform := newFakeWhois(t, func(query string) string { return "domain: " + query + "\r\n" })
+	defer platform.Close()
+
+	w := newTestServer(t, func(cfg *config.Service) {
+		cfg.DomainZoneWhois = map[string]string{
+			"co.uk": registry.Addr(), "msk.ru": registry.Addr(), "ru": ru.Addr(),
+			"io": platform.Addr(), "com": platform.Addr(),
 		}
-		if puny != test.result {
-			t.Errorf("unxpected result for test case #%d: %s <> %s", n, puny, test.result)
+	})
+	defer w.cache.Close()
+
+	answer, err := w.answerQuery(client{}, "shop.customer.example.co.uk")
+	if err != nil || answer != reducedNote("shop.customer.example.co.uk", "example.co.uk")+"domain: example.co.uk\r\n" {
+		t.Errorf("unexpected answer: %q %v", answer, err)
+	}
+	if _, found, _ := w.cache.GetEntry("example.co.uk"); !found {
+		t.Error("registrable domain is not cached")
+	}
+
+	if answer, err := w.answerQuery(client{}, "example.co.uk"); err != nil || answer != "domain: example.co.uk\r\n" {
+		t.Errorf("unexpected answer: %q %v", answer, err)
+	}
+
+	if _, err := w.answerQuery(client{}, "www.shop.msk.ru"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := w.answerQuery(client{}, "sub.example.ru"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if queries := registry.Queries(); len(queries) != 2 || queries[0] != "example.co.uk" || queries[1] != "shop.msk.ru" {
+		t.Errorf("unexpected registry queries: %v", queries)
+	}
+	if queries := ru.Queries(); len(queries) != 1 || queries[0] != "example.ru" {
+		t.Errorf("unexpected ru queries: %v", queries)
+	}
+
+	// суффиксы платформ из секции PRIVATE не публичные: whois спрашивается про сам домен платформы
+	answer, err = w.answerQuery(client{}, "foo.github.io")
+	if err != nil || answer != "% whois-proxy: query foo.github.io, answer for registrable domain github.io\r\ndomain: github.io\r\n" {
+		t.Errorf("unexpected answer: %q %v", answer, err)
+	}
+	for _, query := range []string{"myapp.herokuapp.com", "x.blogspot.com"} {
+		if _, err := w.answerQuery(client{}, query); err != nil {
+			t.Errorf("unexpected error for %s: %v", query, err)
 		}
 	}
+	if queries := platform.Queries(); len(queries) != 3 || queries[0] != "github.io" ||
+		queries[1] != "herokuapp.com" || queries[2] != "blogspot.com" {
+		t.Errorf("unexpected platform queries: %v", queries)
+	}
 }
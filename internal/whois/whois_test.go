@@ -1,6 +1,7 @@
 package whois
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -171,7 +172,7 @@ func Test_getWhoisServer(t *testing.T) {
 	}
 
 	for n, test := range testCases {
-		host, port, err := server.getWhoisServer(test.domain)
+		host, port, err := server.getWhoisServer(context.Background(), test.domain)
 		if test.err == nil && err != nil || test.err != nil && err == nil {
 			t.Fatalf("unxpected error result in #%d test for domain: %s  error: %v  expected err: %v",
 				n, test.domain, err, test.err)
@@ -232,7 +233,7 @@ func Test_WhoisServer_ALL(t *testing.T) {
 	}
 
 	for n, test := range testCases {
-		s, err := server.processRequest(test.domain)
+		s, err := server.processRequest(client{}, test.domain)
 		_ = s
 		if test.err == nil && err != nil || test.err != nil && err == nil {
 			t.Fatalf("unxpected error result in #%d test for domain: %s  error: %v  expected err: %v",
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/pkg/whois"
+)
+
+// Option - настройка Server
+type Option func(*options)
+
+type options struct {
+	cfg    config.Service
+	logger *logrus.Logger
+}
+
+// WithListen - адрес TCP сервера (порт 43), нужен только для Start
+func WithListen(host, port string) Option {
+	return func(o *options) { o.cfg.Host, o.cfg.Port = host, port }
+}
+
+// WithMaxConnections - одновременных клиентских соединений TCP сервера
+func WithMaxConnections(n int) Option {
+	return func(o *options) { o.cfg.MaxCntConnect = n }
+}
+
+// WithUpstreams - whois серверы зон: зона (в любом регистре, Unicode или punycode) -> "host" или "host:port"
+func WithUpstreams(zones map[string]string) Option {
+	return func(o *options) {
+		for zone, server := range zones {
+			o.cfg.DomainZoneWhois[upstream.NormalizeZone(zone)] = upstream.WithPort(server)
+		}
+	}
+}
+
+// WithDefaultUpstream - whois сервер для зон без WithUpstreams (обязательно)
+func WithDefaultUpstream(server string) Option {
+	return func(o *options) { o.cfg.DefaultWhois = upstream.WithPort(server) }
+}
+
+// WithResolvers - источники whois серверов по порядку: "static" (WithUpstreams), "iana", "dns", "rdap",
+// "default" (WithDefaultUpstream). По умолчанию - static, default.
+func WithResolvers(chain ...string) Option {
+	return func(o *options) { o.cfg.Resolvers.Chain = chain }
+}
+
+// WithTimeouts - таймауты запросов к whois серверам (Write округляется вверх до секунд)
+func WithTimeouts(t whois.Timeouts) Option {
+	return func(o *options) {
+		o.cfg.Timeouts.UpstreamTimeouts = upstreamTimeouts(t)
+		if t.Write > 0 {
+			o.cfg.WriteTimeout = int((t.Write + time.Second - 1) / time.Second)
+		}
+	}
+}
+
+// WithUpstreamTimeouts - таймауты запросов к whois серверу "host" или "host:port", незаданные - из WithTimeouts
+func WithUpstreamTimeouts(server string, t whois.Timeouts) Option {
+	return func(o *options) {
+		if o.cfg.Timeouts.Upstreams == nil {
+			o.cfg.Timeouts.Upstreams = map[string]config.UpstreamTimeouts{}
+		}
+		o.cfg.Timeouts.Upstreams[server] = upstreamTimeouts(t)
+	}
+}
+
+// WithRequestTimeout - срок ответа на запрос на все обращения к whois серверам, 0 - без ограничения
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *options) { o.cfg.Timeouts.Request = milliseconds(d) }
+}
+
+// WithCacheTTL - время кэширования ответов whois серверов
+func WithCacheTTL(d time.Duration) Option {
+	return func(o *options) { o.cfg.CacheTTL = int(d / time.Second) }
+}
+
+// WithMaxResponseSize - байт, ответ whois сервера больше - обрезается строкой "% response truncated"
+func WithMaxResponseSize(n int) Option {
+	return func(o *options) { o.cfg.MaxResponseSize = n }
+}
+
+// WithLogger - логгер, по умолчанию logrus.StandardLogger()
+func WithLogger(logger *logrus.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+func upstreamTimeouts(t whois.Timeouts) config.UpstreamTimeouts {
+	return config.UpstreamTimeouts{
+		Dial:      milliseconds(t.Dial),
+		FirstByte: milliseconds(t.FirstByte),
+		Idle:      milliseconds(t.Idle),
+		Total:     milliseconds(t.Total),
+	}
+}
+
+func milliseconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	if d < time.Millisecond {
+		return 1
+	}
+	return int(d / time.Millisecond)
+}
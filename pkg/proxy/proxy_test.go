@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/pkg/whois"
+)
+
+func TestServer_Lookup(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			query, _ := bufio.NewReader(conn).ReadString('\n')
+			_, _ = io.WriteString(conn, "domain:        "+strings.TrimSpace(query)+"\r\n")
+			_ = conn.Close()
+		}
+	}()
+
+	if _, err := New(); err == nil {
+		t.Error("no error without default upstream")
+	}
+
+	s, err := New(
+		WithListen("127.0.0.1", "0"),
+		WithDefaultUpstream("127.0.0.1:1"),
+		WithUpstreams(map[string]string{"ru": l.Addr().String(), ".РФ.": l.Addr().String()}),
+		WithTimeouts(whois.Timeouts{Dial: time.Second, Write: time.Second, FirstByte: time.Second, Idle: time.Second}),
+		WithRequestTimeout(2*time.Second),
+		WithLogger(&logrus.Logger{}),
+	)
+	if err != nil {
+		t.Fatalf("proxy server not created: %v", err)
+	}
+	defer s.Stop()
+
+	answer, err := s.Lookup(context.Background(), "Sub.Example.RU")
+	if err != nil || answer.Domain != "example.ru" || answer.Class != ClassFound || answer.Cached ||
+		answer.Raw != "domain:        example.ru\r\n" || !strings.HasSuffix(answer.Text, answer.Raw) {
+		t.Errorf("unexpected answer: %+v %v", answer, err)
+	}
+
+	if answer, err := s.Lookup(context.Background(), "example.ru"); err != nil || !answer.Cached {
+		t.Errorf("answer is not cached: %+v %v", answer, err)
+	}
+
+	// зона из WithUpstreams в Unicode и верхнем регистре
+	if answer, err := s.Lookup(context.Background(), "окна.рф"); err != nil || answer.Raw != "domain:        xn--80atjc.xn--p1ai\r\n" {
+		t.Errorf("unexpected answer for IDN zone: %+v %v", answer, err)
+	}
+
+	if _, err := s.Lookup(context.Background(), "-bad-.ru"); whois.ClassOf(err) != whois.InvalidQuery {
+		t.Errorf("unexpected error for invalid query: %v", err)
+	}
+	if _, err := s.Lookup(context.Background(), "example.com"); whois.ClassOf(err) != whois.UpstreamRefused {
+		t.Errorf("unexpected error for unavailable upstream: %v", err)
+	}
+}
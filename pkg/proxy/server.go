@@ -0,0 +1,100 @@
+// Package proxy - whois proxy для встраивания: тот же выбор whois сервера, кэш и пост-обработка ответов,
+// что у сервиса whois-proxy, настраивается в коде без YAML. Start - TCP сервер (порт 43), Lookup - ответ без него.
+package proxy
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/config"
+	whois_server "gitlab.esta.spb.ru/arseny/whois-proxy/internal/whois"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/pkg/whois"
+)
+
+// Answer - ответ на Lookup: текст как для клиента TCP и сырой ответ whois сервера
+type Answer struct {
+	Query     string
+	Domain    string // регистрируемый домен (punycode), для которого запрошен whois
+	Upstream  string // host:port whois сервера
+	Class     ResultClass
+	Raw       string
+	Text      string
+	FetchedAt time.Time
+	Cached    bool
+}
+
+// ResultClass - класс ответа whois сервера
+type ResultClass string
+
+const (
+	ClassFound    = ResultClass(whois_server.ClassFound)
+	ClassNotFound = ResultClass(whois_server.ClassNotFound)
+	ClassError    = ResultClass(whois_server.ClassError)
+)
+
+type Server struct {
+	w *whois_server.ProxyWhoisServer
+}
+
+func New(opts ...Option) (*Server, error) {
+	o := options{
+		cfg: config.Service{
+			Host:            "0.0.0.0",
+			Port:            "43",
+			MaxCntConnect:   100,
+			MaxLenBuffer:    4096,
+			ReadTimeout:     30,
+			WriteTimeout:    30,
+			CacheTTL:        300,
+			DomainZoneWhois: map[string]string{},
+		},
+		logger: logrus.StandardLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.cfg.DefaultWhois == "" {
+		return nil, errors.New("default upstream is required")
+	}
+
+	w, err := whois_server.NewWhoisProxyServer(&o.cfg, o.logger)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't create whois proxy server")
+	}
+
+	return &Server{w: w}, nil
+}
+
+// Start - TCP сервер и фоновые задачи (очистка кэша и т.д.)
+func (s *Server) Start() error {
+	return s.w.Start()
+}
+
+// Stop - остановить TCP сервер (если запущен) и закрыть кэш
+func (s *Server) Stop() error {
+	return s.w.Stop()
+}
+
+// Lookup - ответ на запрос домена. Ошибки - *whois.Error (класс - whois.ClassOf).
+func (s *Server) Lookup(ctx context.Context, query string) (Answer, error) {
+	answer, err := s.w.Lookup(ctx, query)
+	return newAnswer(answer), whois.WrapError(err, answer.Upstream)
+}
+
+func newAnswer(a whois_server.Answer) Answer {
+	return Answer{
+		Query:     a.Query,
+		Domain:    a.Domain,
+		Upstream:  a.Upstream,
+		Class:     ResultClass(a.Class),
+		Raw:       a.Raw,
+		Text:      a.Text,
+		FetchedAt: a.FetchedAt,
+		Cached:    a.Cached,
+	}
+}
@@ -0,0 +1,163 @@
+// Package whois - whois клиент для встраивания в другие сервисы: выбор whois сервера по зоне,
+// таймауты, ссылки на следующий whois сервер. Ошибки - *Error со стабильными классами (см. ClassOf).
+package whois
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/idna"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/hostname"
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+const (
+	DefaultUpstream     = "whois.iana.org:43"
+	DefaultMaxReferrals = 2 // IANA -> реестр -> регистратор
+)
+
+// Timeouts - таймауты запроса к whois серверу, 0 - без ограничения
+type Timeouts struct {
+	Dial      time.Duration // установка соединения
+	Write     time.Duration // отправка запроса
+	FirstByte time.Duration // от отправки запроса до первого байта ответа
+	Idle      time.Duration // между частями ответа
+	Total     time.Duration // весь запрос: соединение, отправка и чтение ответа
+}
+
+// DefaultTimeouts - таймауты Client по умолчанию
+var DefaultTimeouts = Timeouts(upstream.DefaultTimeouts)
+
+// Dialer - установка соединения с whois сервером, например *net.Dialer
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// Response - ответ на Lookup
+type Response struct {
+	Domain  string   // домен запроса (punycode)
+	Server  string   // host:port whois сервера, чей ответ в Raw (последний для ReferralAll)
+	Servers []string // все опрошенные whois серверы по порядку
+	Raw     string
+}
+
+// Client - whois клиент. Без опций: IANA и ссылки до реестра и регистратора.
+type Client struct {
+	timeouts        Timeouts
+	dialer          Dialer
+	zones           map[string]string // зона (punycode) -> host:port
+	defaultUpstream string
+	referrals       ReferralPolicy
+	maxReferrals    int
+	maxResponseSize int
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		timeouts:        DefaultTimeouts,
+		dialer:          &net.Dialer{},
+		zones:           map[string]string{},
+		defaultUpstream: DefaultUpstream,
+		referrals:       ReferralFollow,
+		maxReferrals:    DefaultMaxReferrals,
+		maxResponseSize: upstream.ResponseSizeDefault,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Lookup - whois домена: whois сервер зоны (самая длинная подходящая зона, затем сервер по умолчанию)
+// и ссылки из ответа по ReferralPolicy. При ошибке по ссылке возвращается и ответ, полученный до нее.
+func (c *Client) Lookup(ctx context.Context, domain string) (*Response, error) {
+	fqdn, err := queryDomain(domain)
+	if err != nil {
+		return nil, &Error{Class: InvalidQuery, Err: err}
+	}
+
+	server := c.upstream(fqdn)
+	if server == "" {
+		return nil, &Error{Class: UnsupportedTLD, Err: errors.Errorf("no whois server for %s", fqdn)}
+	}
+
+	raw, err := c.Query(ctx, server, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	res := &Response{Domain: fqdn, Server: server, Servers: []string{server}, Raw: raw}
+
+	for hop := 0; c.referrals != ReferralNone && hop < c.maxReferrals; hop++ {
+		next := Referral(raw)
+		if next == "" {
+			break
+		}
+		next = upstream.WithPort(next)
+		if contains(res.Servers, next) {
+			break
+		}
+
+		res.Servers = append(res.Servers, next)
+		if raw, err = c.Query(ctx, next, fqdn); err != nil {
+			return res, err
+		}
+
+		res.Server = next
+		if c.referrals == ReferralAll {
+			res.Raw += "\r\n" + raw
+		} else {
+			res.Raw = raw
+		}
+	}
+
+	return res, nil
+}
+
+// Query - запрос query к whois серверу server ("host" или "host:port") как есть
+func (c *Client) Query(ctx context.Context, server, query string) (string, error) {
+	server = upstream.WithPort(server)
+	raw, err := upstream.Exchange(ctx, c.dialer.DialContext, server, query, upstream.Timeouts(c.timeouts), nil, c.maxResponseSize)
+	return raw, WrapError(err, server)
+}
+
+// upstream - whois сервер для домена: самая длинная подходящая зона, затем сервер по умолчанию
+func (c *Client) upstream(fqdn string) string {
+	for zone := fqdn; zone != ""; {
+		if server, ok := c.zones[zone]; ok {
+			return server
+		}
+		i := strings.Index(zone, ".")
+		if i < 0 {
+			break
+		}
+		zone = zone[i+1:]
+	}
+	return c.defaultUpstream
+}
+
+// queryDomain - домен запроса в punycode после нормализации и проверки
+func queryDomain(query string) (string, error) {
+	fqdn, err := idna.Lookup.ToASCII(hostname.Normalize(query, true))
+	if err != nil {
+		return "", errors.WithMessagef(err, "can't convert %q to punycode", query)
+	}
+	if err := (hostname.Rules{}).Validate(fqdn); err != nil {
+		return "", err
+	}
+	return fqdn, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package whois
+
+import (
+	"fmt"
+
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+// ErrorClass - класс ошибки, значения стабильны и совпадают с классами errorTemplates прокси
+type ErrorClass string
+
+const (
+	InvalidQuery    = ErrorClass(upstream.ErrorInvalidQuery)
+	UnsupportedTLD  = ErrorClass(upstream.ErrorUnsupportedTLD)
+	UpstreamTimeout = ErrorClass(upstream.ErrorUpstreamTimeout)
+	UpstreamRefused = ErrorClass(upstream.ErrorUpstreamRefused)
+	RateLimited     = ErrorClass(upstream.ErrorRateLimited)
+	Internal        = ErrorClass(upstream.ErrorInternal)
+)
+
+// Error - ошибка Client и proxy.Server
+type Error struct {
+	Class  ErrorClass
+	Server string // host:port whois сервера, если ошибка при обращении к нему
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Server != "" {
+		return fmt.Sprintf("whois %s (%s): %v", e.Class, e.Server, e.Err)
+	}
+	return fmt.Sprintf("whois %s: %v", e.Class, e.Err)
+}
+
+// Cause - для github.com/pkg/errors
+func (e *Error) Cause() error { return e.Err }
+
+// Unwrap - для errors.Is / errors.As
+func (e *Error) Unwrap() error { return e.Err }
+
+// Timeout - для net.Error
+func (e *Error) Timeout() bool { return e.Class == UpstreamTimeout }
+
+// WrapError - ошибка err как *Error с классом по первопричине, nil - nil
+func WrapError(err error, server string) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Class: ErrorClass(upstream.ClassifyError(err)), Server: server, Err: err}
+}
+
+// ClassOf - класс ошибки: первой *Error в цепочке (errors.WithMessage и т.д.), иначе по первопричине
+func ClassOf(err error) ErrorClass {
+	type causer interface {
+		Cause() error
+	}
+
+	for e := err; e != nil; {
+		if werr, ok := e.(*Error); ok {
+			return werr.Class
+		}
+		c, ok := e.(causer)
+		if !ok {
+			break
+		}
+		e = c.Cause()
+	}
+
+	return ErrorClass(upstream.ClassifyError(err))
+}
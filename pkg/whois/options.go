@@ -0,0 +1,50 @@
+package whois
+
+import (
+	"gitlab.esta.spb.ru/arseny/whois-proxy/internal/upstream"
+)
+
+// Option - настройка Client
+type Option func(*Client)
+
+// WithTimeouts - таймауты каждого запроса к whois серверу (в том числе по ссылкам), общий срок - контекст запроса
+func WithTimeouts(t Timeouts) Option {
+	return func(c *Client) { c.timeouts = t }
+}
+
+// WithDialer - установка соединения с whois серверами (прокси, выбор исходящего адреса и т.д.)
+func WithDialer(d Dialer) Option {
+	return func(c *Client) {
+		if d != nil {
+			c.dialer = d
+		}
+	}
+}
+
+// WithUpstreams - whois серверы зон: зона (в том числе IDN, "рф") -> "host" или "host:port"
+func WithUpstreams(zones map[string]string) Option {
+	return func(c *Client) {
+		for zone, server := range zones {
+			c.zones[upstream.NormalizeZone(zone)] = upstream.WithPort(server)
+		}
+	}
+}
+
+// WithDefaultUpstream - whois сервер для зон без WithUpstreams, пусто - такие зоны не поддерживаются (UnsupportedTLD)
+func WithDefaultUpstream(server string) Option {
+	return func(c *Client) { c.defaultUpstream = upstream.WithPort(server) }
+}
+
+// WithReferrals - переход по ссылкам на следующий whois сервер, не больше maxHops переходов
+func WithReferrals(policy ReferralPolicy, maxHops int) Option {
+	return func(c *Client) { c.referrals, c.maxReferrals = policy, maxHops }
+}
+
+// WithMaxResponseSize - байт, ответ whois сервера больше - обрезается строкой "% response truncated"
+func WithMaxResponseSize(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxResponseSize = n
+		}
+	}
+}
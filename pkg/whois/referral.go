@@ -0,0 +1,55 @@
+package whois
+
+import (
+	"bufio"
+	"strings"
+)
+
+// ReferralPolicy - что делать со ссылкой на следующий whois сервер в ответе (IANA "refer:", "Registrar WHOIS Server:")
+type ReferralPolicy int
+
+const (
+	ReferralNone   ReferralPolicy = iota // ответ первого whois сервера
+	ReferralFollow                       // ответ последнего whois сервера по ссылкам
+	ReferralAll                          // ответы всех whois серверов по ссылкам подряд
+)
+
+// referralFields - поля со ссылкой на следующий whois сервер (lower case)
+var referralFields = map[string]bool{
+	"refer":                  true, // IANA
+	"whois":                  true, // IANA, запись зоны
+	"whois server":           true,
+	"registrar whois server": true, // ICANN формат
+	"referralserver":         true, // RIR
+}
+
+// Referral - whois сервер, на который ссылается ответ raw, пусто - ссылки нет
+func Referral(raw string) string {
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '%' || line[0] == '#' {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 || !referralFields[strings.ToLower(strings.TrimSpace(line[:i]))] {
+			continue
+		}
+
+		value := strings.TrimSpace(line[i+1:])
+		if strings.Contains(value, "://") {
+			if !strings.HasPrefix(strings.ToLower(value), "whois://") { // rwhois:// и т.д. не поддерживаются
+				continue
+			}
+			value = value[len("whois://"):]
+		}
+		value = strings.ToLower(strings.TrimSuffix(value, "/"))
+
+		if value != "" && !strings.ContainsAny(value, " \t/") {
+			return value
+		}
+	}
+
+	return ""
+}
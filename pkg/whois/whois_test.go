@@ -0,0 +1,139 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fakeWhois - whois сервер с ответом answer(query)
+func fakeWhois(t *testing.T, answer func(query string) string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				_, _ = io.WriteString(conn, answer(strings.TrimSpace(query)))
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestReferral(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		expected string
+	}{
+		{"% IANA WHOIS server\nrefer:        whois.tcinet.ru\n\ndomain:       RU\n", "whois.tcinet.ru"},
+		{"   Domain Name: EXAMPLE.COM\r\n   Registrar WHOIS Server: whois.iana.org\r\n", "whois.iana.org"},
+		{"ReferralServer:  whois://whois.ripe.net\n", "whois.ripe.net"},
+		{"ReferralServer:  rwhois://rwhois.example.net:4321\n", ""},
+		{"% refer: whois.example.net\ndomain: EXAMPLE.RU\n", ""},
+		{"Registrar WHOIS Server: \n", ""},
+	}
+
+	for n, test := range testCases {
+		if referral := Referral(test.raw); referral != test.expected {
+			t.Errorf("unexpected referral for test case #%d: %q", n, referral)
+		}
+	}
+}
+
+func TestClient_Lookup(t *testing.T) {
+	registrar := fakeWhois(t, func(query string) string { return "Domain Name: " + query + "\r\nRegistrant: Example\r\n" })
+	defer registrar.Close()
+
+	registry := fakeWhois(t, func(query string) string {
+		return "Domain Name: " + query + "\r\nRegistrar WHOIS Server: " + registrar.Addr().String() + "\r\n"
+	})
+	defer registry.Close()
+
+	root := fakeWhois(t, func(query string) string { return "refer: " + registry.Addr().String() + "\n" })
+	defer root.Close()
+
+	c := New(WithDefaultUpstream(root.Addr().String()))
+	res, err := c.Lookup(context.Background(), "Sub.Example.COM")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if res.Domain != "sub.example.com" || res.Server != registrar.Addr().String() || len(res.Servers) != 3 ||
+		res.Raw != "Domain Name: sub.example.com\r\nRegistrant: Example\r\n" {
+		t.Errorf("unexpected response: %+v", res)
+	}
+
+	c = New(WithUpstreams(map[string]string{"com": registry.Addr().String()}), WithReferrals(ReferralNone, 0))
+	if res, err := c.Lookup(context.Background(), "example.com"); err != nil || res.Server != registry.Addr().String() || len(res.Servers) != 1 {
+		t.Errorf("unexpected response without referrals: %+v %v", res, err)
+	}
+
+	c = New(WithUpstreams(map[string]string{"com": registry.Addr().String()}), WithReferrals(ReferralAll, 1))
+	if res, err := c.Lookup(context.Background(), "example.com"); err != nil || !strings.HasPrefix(res.Raw, "Domain Name: example.com\r\nRegistrar WHOIS Server:") ||
+		!strings.HasSuffix(res.Raw, "Registrant: Example\r\n") {
+		t.Errorf("unexpected response with all referrals: %+v %v", res, err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	defer silent.Close()
+	go func() {
+		conn, err := silent.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(time.Second)
+	}()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	refusedAddr := closed.Addr().String()
+	_ = closed.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	testCases := []struct {
+		client *Client
+		ctx    context.Context
+		domain string
+		class  ErrorClass
+	}{
+		{New(), context.Background(), "-bad-.com", InvalidQuery},
+		{New(WithDefaultUpstream("")), context.Background(), "example.com", UnsupportedTLD},
+		{New(WithDefaultUpstream(refusedAddr)), context.Background(), "example.com", UpstreamRefused},
+		{New(WithDefaultUpstream(silent.Addr().String())), ctx, "example.com", UpstreamTimeout},
+	}
+
+	for n, test := range testCases {
+		_, err := test.client.Lookup(test.ctx, test.domain)
+		if _, ok := err.(*Error); !ok || ClassOf(errors.WithMessage(err, "lookup")) != test.class {
+			t.Errorf("unexpected error for test case #%d: %v", n, err)
+		}
+	}
+}